@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"os/exec"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rising3/go-cli/internal/cmd/cat"
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/echo"
+	"github.com/rising3/go-cli/internal/proc"
+	"github.com/rising3/go-cli/internal/secret"
+	"github.com/rising3/go-cli/internal/stdio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// App is an instance of mycli. It owns the configuration and everything
+// needed to load it, the standard streams, the config directories and the
+// process runner, so that several instances can live in one process. Create
+// it with NewApp, adjust the exported fields and build its command tree with
+// NewRootCommand.
+type App struct {
+	// Streams are the standard streams of the commands and of the messages
+	// printed while loading the configuration.
+	Streams stdio.Streams
+
+	// ConfigDir is the user config directory; GetConfigPath() when empty.
+	ConfigDir string
+
+	// SystemConfigDirs are the system-wide config directories in ascending
+	// precedence; GetSystemConfigPaths() when nil.
+	SystemConfigDirs []string
+
+	// ExecCommand builds the processes the app runs, such as the editor and
+	// exec: secret references.
+	ExecCommand func(name string, arg ...string) *exec.Cmd
+
+	// Echo, Cat and Configure implement the commands of the same name. Tests
+	// replace them with stubs.
+	Echo      func(opts echo.EchoOptions) error
+	Cat       func(filenames []string, opts cat.Options) error
+	Configure func(target string, opts configure.ConfigureOptions) error
+
+	// Config is the configuration loaded before a command runs. Use
+	// CurrentConfig to observe reloads made by WatchConfig. Fields holding a
+	// secret reference or an encrypted value are only resolved for commands
+	// carrying resolveReferencesAnnotation and are empty otherwise.
+	Config Config
+
+	viper *viper.Viper
+	root  *cobra.Command
+
+	cfgFile  string
+	profile  string
+	verbose  bool
+	noConfig bool // skip reading every config file; set with --no-config or MYCLI_NO_CONFIG
+
+	// resolveRefs enables resolving secret references and decrypting secrets
+	// while decoding the configuration; see resolveReferencesAnnotation.
+	resolveRefs bool
+
+	// profileSource records where the active profile was selected from:
+	// "flag", "env", "stored" (via `profile use`), or "" when none was selected.
+	profileSource string
+
+	// layers lists the config files read by initConfig, in merge order.
+	layers []configLayer
+
+	// current holds the configuration loaded by initConfig and replaced by
+	// every successful reload.
+	current atomic.Pointer[Config]
+
+	// reloadMu serializes reloads, which rebuild the viper instance.
+	reloadMu sync.Mutex
+
+	subscribersMu  sync.Mutex
+	subscribers    map[int]func(Config)
+	nextSubscriber int
+}
+
+// NewApp returns an App bound to the process' stdio and the default config
+// directories.
+func NewApp() *App {
+	vp := viper.New()
+	vp.SetEnvPrefix(strings.ToUpper(CliName))
+	vp.SetEnvKeyReplacer(envKeyReplacer)
+	vp.AutomaticEnv()
+
+	return &App{
+		Streams:     stdio.NewDefault(),
+		ExecCommand: proc.ExecCommand,
+		Echo:        echo.Echo,
+		Cat:         cat.Cat,
+		Configure:   configure.Configure,
+		viper:       vp,
+		subscribers: map[int]func(Config){},
+	}
+}
+
+// configDir returns the user config directory of the app.
+func (a *App) configDir() string {
+	if a.ConfigDir != "" {
+		return a.ConfigDir
+	}
+	return GetConfigPath()
+}
+
+// systemConfigDirs returns the system-wide config directories of the app.
+func (a *App) systemConfigDirs() []string {
+	if a.SystemConfigDirs != nil {
+		return a.SystemConfigDirs
+	}
+	return GetSystemConfigPaths()
+}
+
+// resolver returns the secret.Resolver running exec: references with the
+// app's process runner.
+func (a *App) resolver() secret.Resolver {
+	return secret.Resolver{ExecCommand: a.ExecCommand}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/stdio"
+)
+
+// newTestApp returns an App reading its config from dir, with buffered
+// streams.
+func newTestApp(t *testing.T, dir string) (*App, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	app := NewApp()
+	app.ConfigDir = dir
+	app.SystemConfigDirs = []string{}
+	app.Streams = stdio.Streams{In: bytes.NewReader(nil), Out: &out, Err: &bytes.Buffer{}}
+	return app, &out
+}
+
+// TestApp_IndependentInstances verifies that two apps in one process load
+// their own config directory and profile.
+func TestApp_IndependentInstances(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+
+	dirA, dirB := t.TempDir(), t.TempDir()
+	for dir, content := range map[string]string{
+		filepath.Join(dirA, "default.yaml"): "client-id: a\n",
+		filepath.Join(dirB, "default.yaml"): "client-id: b\n",
+		filepath.Join(dirB, "dev.yaml"):     "client-id: b-dev\n",
+	} {
+		if err := os.WriteFile(dir, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	appA, outA := newTestApp(t, dirA)
+	appB, outB := newTestApp(t, dirB)
+	rootA, rootB := NewRootCommand(appA), NewRootCommand(appB)
+
+	rootB.SetArgs([]string{"--profile", "dev", "config", "get", "client-id"})
+	if err := rootB.Execute(); err != nil {
+		t.Fatalf("app B failed: %v", err)
+	}
+	rootA.SetArgs([]string{"config", "get", "client-id"})
+	if err := rootA.Execute(); err != nil {
+		t.Fatalf("app A failed: %v", err)
+	}
+
+	if outA.String() != "a\n" || outB.String() != "b-dev\n" {
+		t.Errorf("outputs = %q, %q; want a, b-dev", outA, outB)
+	}
+	if appA.Config.ClientID != "a" || appB.Config.ClientID != "b-dev" {
+		t.Errorf("ClientID = %q, %q; want a, b-dev", appA.Config.ClientID, appB.Config.ClientID)
+	}
+	if appA.activeProfile() != DefaultProfile {
+		t.Errorf("app A profile = %q; want %q", appA.activeProfile(), DefaultProfile)
+	}
+}
+
+// TestApp_ExecCommand verifies that exec: references run through the app's
+// process runner.
+func TestApp_ExecCommand(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "default.yaml"), []byte("client-secret: exec:vault read\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApp(t, dir)
+	var ran string
+	app.ExecCommand = func(name string, arg ...string) *exec.Cmd {
+		ran = name
+		return exec.Command("echo", "stubbed")
+	}
+
+	root := NewRootCommand(app)
+	root.SetArgs([]string{"config", "show"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if ran != "vault" || app.Config.ClientSecret != "stubbed" {
+		t.Errorf("ran %q, ClientSecret = %q; want vault, stubbed", ran, app.Config.ClientSecret)
+	}
+}
+
+// TestApp_ReferencesAreLazyAndTrusted verifies that exec: references only run
+// for commands reading their values, and never when they come from, or
+// interpolate a value of, the project config file.
+func TestApp_ReferencesAreLazyAndTrusted(t *testing.T) {
+	project := t.TempDir()
+	t.Chdir(project)
+	t.Setenv("MYCLI_PROFILE", "")
+
+	dir := t.TempDir()
+	user := "client-id: exec:echo ${hoge.fuga}\ncommon:\n  var1: exec:vault read\n"
+	if err := os.WriteFile(filepath.Join(dir, "default.yaml"), []byte(user), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	local := "client-secret: exec:touch pwned\nhoge:\n  fuga: evil\n"
+	if err := os.WriteFile(filepath.Join(project, GetProjectConfigFile()), []byte(local), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var ran []string
+	run := func(args ...string) error {
+		app, _ := newTestApp(t, dir)
+		app.ExecCommand = func(name string, arg ...string) *exec.Cmd {
+			ran = append(ran, name)
+			return exec.Command("echo", "stubbed")
+		}
+		root := NewRootCommand(app)
+		root.SetArgs(args)
+		return root.Execute()
+	}
+
+	if err := run("echo", "hi"); err != nil || len(ran) != 0 {
+		t.Fatalf("echo ran %v: %v", ran, err)
+	}
+
+	err := run("config", "show")
+	if strings.Join(ran, ",") != "vault" {
+		t.Errorf("config show ran %v; want only vault", ran)
+	}
+	if ExitCode(err) != ExitConfigInvalid || strings.Count(err.Error(), "references are not allowed in the project config file") != 2 {
+		t.Errorf("expected client-id and client-secret to be rejected, got: %v", err)
+	}
+}
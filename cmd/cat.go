@@ -5,10 +5,11 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var catCmd = &cobra.Command{
-	Use:   "cat [flags] [file...]",
-	Short: "Concatenate files and print on the standard output",
-	Long: `Concatenate FILE(s) to standard output.
+func newCatCommand(app *App) *cobra.Command {
+	catCmd := &cobra.Command{
+		Use:   "cat [flags] [file...]",
+		Short: "Concatenate files and print on the standard output",
+		Long: `Concatenate FILE(s) to standard output.
 
 With no FILE, or when FILE is -, read standard input.
 
@@ -22,18 +23,18 @@ Examples:
   mycli cat -T file.txt         # Show tabs as ^I
   mycli cat -v file.txt         # Show control characters
   mycli cat -A file.txt         # Show all (equivalent to -vET)`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		opts, err := cat.NewOptions(cmd)
-		if err != nil {
-			return err
-		}
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts, err := cat.NewOptions(cmd)
+			if err != nil {
+				return err
+			}
+			opts.Input = cmd.InOrStdin()
+			opts.Output = cmd.OutOrStdout()
+			opts.ErrOutput = cmd.ErrOrStderr()
 
-		return cat.CatFunc(args, opts)
-	},
-}
-
-func init() {
-	rootCmd.AddCommand(catCmd)
+			return app.Cat(args, opts)
+		},
+	}
 
 	catCmd.Flags().BoolP("number", "n", false, "number all output lines")
 	catCmd.Flags().BoolP("number-nonblank", "b", false, "number nonempty output lines")
@@ -41,4 +42,5 @@ func init() {
 	catCmd.Flags().BoolP("show-tabs", "T", false, "display TAB characters as ^I")
 	catCmd.Flags().BoolP("show-nonprinting", "v", false, "use ^ and M- notation")
 	catCmd.Flags().BoolP("show-all", "A", false, "equivalent to -vET")
+	return catCmd
 }
@@ -24,17 +24,17 @@ func TestCatCommand_BasicFile(t *testing.T) {
 	}
 	_ = tmpfile.Close()
 
-	// Mock CatFunc
+	// Mock app.Cat
 	var calledFilenames []string
 
-	oldCatFunc := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledFilenames = filenames
 		return nil
 	}
-	defer func() { cat.CatFunc = oldCatFunc }()
 
 	// Execute command
+	rootCmd := NewRootCommand(app)
 	rootCmd.SetArgs([]string{"cat", tmpfile.Name()})
 	var output bytes.Buffer
 	rootCmd.SetOut(&output)
@@ -44,7 +44,7 @@ func TestCatCommand_BasicFile(t *testing.T) {
 		t.Fatalf("Execute() failed: %v", err)
 	}
 
-	// Verify CatFunc was called with correct args
+	// Verify app.Cat was called with correct args
 	if len(calledFilenames) != 1 || calledFilenames[0] != tmpfile.Name() {
 		t.Errorf("Expected filenames [%s], got %v", tmpfile.Name(), calledFilenames)
 	}
@@ -52,17 +52,17 @@ func TestCatCommand_BasicFile(t *testing.T) {
 
 // T025 [P] [US2] TestCatCommand_StdinOnly - no args should read from stdin
 func TestCatCommand_StdinOnly(t *testing.T) {
-	// Mock CatFunc
+	// Mock app.Cat
 	var calledFilenames []string
 
-	oldCatFunc := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledFilenames = filenames
 		return nil
 	}
-	defer func() { cat.CatFunc = oldCatFunc }()
 
 	// Execute with no file args (stdin mode)
+	rootCmd := NewRootCommand(app)
 	rootCmd.SetArgs([]string{"cat"})
 	var output bytes.Buffer
 	rootCmd.SetOut(&output)
@@ -72,7 +72,7 @@ func TestCatCommand_StdinOnly(t *testing.T) {
 		t.Fatalf("Execute() failed: %v", err)
 	}
 
-	// Verify CatFunc was called with empty filenames (stdin mode)
+	// Verify app.Cat was called with empty filenames (stdin mode)
 	if len(calledFilenames) != 0 {
 		t.Errorf("Expected empty filenames for stdin, got %v", calledFilenames)
 	}
@@ -92,17 +92,17 @@ func TestCatCommand_MixedStdinAndFiles(t *testing.T) {
 	}
 	_ = tmpfile.Close()
 
-	// Mock CatFunc
+	// Mock app.Cat
 	var calledFilenames []string
 
-	oldCatFunc := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledFilenames = filenames
 		return nil
 	}
-	defer func() { cat.CatFunc = oldCatFunc }()
 
 	// Execute with mix of file and "-" (stdin)
+	rootCmd := NewRootCommand(app)
 	rootCmd.SetArgs([]string{"cat", tmpfile.Name(), "-", tmpfile.Name()})
 	var output bytes.Buffer
 	rootCmd.SetOut(&output)
@@ -140,17 +140,17 @@ func TestCatCommand_NumberFlag(t *testing.T) {
 	}
 	_ = tmpfile.Close()
 
-	// Mock CatFunc
+	// Mock app.Cat
 	var calledOpts cat.Options
 
-	oldCatFunc := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledOpts = opts
 		return nil
 	}
-	defer func() { cat.CatFunc = oldCatFunc }()
 
 	// Execute with -n flag
+	rootCmd := NewRootCommand(app)
 	rootCmd.SetArgs([]string{"cat", "-n", tmpfile.Name()})
 	var output bytes.Buffer
 	rootCmd.SetOut(&output)
@@ -180,17 +180,17 @@ func TestCatCommand_PartialError(t *testing.T) {
 	}
 	_ = tmpfile.Close()
 
-	// Mock CatFunc to simulate partial error
+	// Mock app.Cat to simulate partial error
 	var calledFilenames []string
-	oldCatFunc := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledFilenames = filenames
 		// Simulate the actual behavior: process valid files, error on invalid
 		return fmt.Errorf("one or more files failed")
 	}
-	defer func() { cat.CatFunc = oldCatFunc }()
 
 	// Execute with mix of valid and invalid files
+	rootCmd := NewRootCommand(app)
 	rootCmd.SetArgs([]string{"cat", tmpfile.Name(), "/nonexistent.txt", tmpfile.Name()})
 	var output bytes.Buffer
 	rootCmd.SetOut(&output)
@@ -203,7 +203,7 @@ func TestCatCommand_PartialError(t *testing.T) {
 		t.Errorf("Expected error for nonexistent file, got nil")
 	}
 
-	// Verify all filenames were passed to CatFunc
+	// Verify all filenames were passed to app.Cat
 	if len(calledFilenames) != 3 {
 		t.Errorf("Expected 3 filenames, got %d", len(calledFilenames))
 	}
@@ -218,6 +218,7 @@ func TestCatCommand_EmptyFile(t *testing.T) {
 	}
 	_ = tmpfile.Close()
 
+	rootCmd := NewRootCommand(NewApp())
 	rootCmd.SetArgs([]string{"cat", tmpfile.Name()})
 	var output bytes.Buffer
 	rootCmd.SetOut(&output)
@@ -9,18 +9,17 @@ import (
 )
 
 func TestCatWrapperCallsInternal(t *testing.T) {
-	// capture arguments passed to CatFunc
+	// capture arguments passed to app.Cat
 	var calledOptions cat.Options
 	var calledFilenames []string
 
 	// stub internal implementation
-	old := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledFilenames = filenames
 		calledOptions = opts
 		return nil
 	}
-	defer func() { cat.CatFunc = old }()
 
 	// Create command with flags
 	cmd := &cobra.Command{}
@@ -45,11 +44,11 @@ func TestCatWrapperCallsInternal(t *testing.T) {
 
 	// Execute
 	args := []string{"file1.txt", "file2.txt"}
-	if err := catCmd.RunE(cmd, args); err != nil {
+	if err := newCatCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("cat RunE failed: %v", err)
 	}
 
-	// Verify internal CatFunc was called with correct options
+	// Verify app.Cat was called with correct options
 	if !calledOptions.NumberAll {
 		t.Errorf("expected NumberAll true, got false")
 	}
@@ -76,12 +75,11 @@ func TestCatWrapper_DefaultFlags(t *testing.T) {
 	var calledOptions cat.Options
 
 	// stub internal implementation
-	old := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledOptions = opts
 		return nil
 	}
-	defer func() { cat.CatFunc = old }()
 
 	// Create command with flags at default values
 	cmd := &cobra.Command{}
@@ -97,7 +95,7 @@ func TestCatWrapper_DefaultFlags(t *testing.T) {
 
 	// Execute with default flags
 	args := []string{"test.txt"}
-	if err := catCmd.RunE(cmd, args); err != nil {
+	if err := newCatCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("cat RunE failed: %v", err)
 	}
 
@@ -123,12 +121,11 @@ func TestCatWrapper_ShowAllFlag(t *testing.T) {
 	var calledOptions cat.Options
 
 	// stub internal implementation
-	old := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledOptions = opts
 		return nil
 	}
-	defer func() { cat.CatFunc = old }()
 
 	// Create command with show-all flag enabled
 	cmd := &cobra.Command{}
@@ -148,7 +145,7 @@ func TestCatWrapper_ShowAllFlag(t *testing.T) {
 
 	// Execute
 	args := []string{"test.txt"}
-	if err := catCmd.RunE(cmd, args); err != nil {
+	if err := newCatCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("cat RunE failed: %v", err)
 	}
 
@@ -168,12 +165,11 @@ func TestCatWrapper_NumberNonBlankFlag(t *testing.T) {
 	var calledOptions cat.Options
 
 	// stub internal implementation
-	old := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledOptions = opts
 		return nil
 	}
-	defer func() { cat.CatFunc = old }()
 
 	// Create command with number-nonblank flag enabled
 	cmd := &cobra.Command{}
@@ -193,7 +189,7 @@ func TestCatWrapper_NumberNonBlankFlag(t *testing.T) {
 
 	// Execute
 	args := []string{"test.txt"}
-	if err := catCmd.RunE(cmd, args); err != nil {
+	if err := newCatCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("cat RunE failed: %v", err)
 	}
 
@@ -210,12 +206,11 @@ func TestCatWrapper_EmptyArgs(t *testing.T) {
 	var calledFilenames []string
 
 	// stub internal implementation
-	old := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledFilenames = filenames
 		return nil
 	}
-	defer func() { cat.CatFunc = old }()
 
 	// Create command
 	cmd := &cobra.Command{}
@@ -230,7 +225,7 @@ func TestCatWrapper_EmptyArgs(t *testing.T) {
 	cmd.Flags().BoolP("show-all", "A", false, "equivalent to -vET")
 
 	// Execute with empty args (should read from stdin)
-	if err := catCmd.RunE(cmd, []string{}); err != nil {
+	if err := newCatCommand(app).RunE(cmd, []string{}); err != nil {
 		t.Fatalf("cat RunE failed: %v", err)
 	}
 
@@ -244,12 +239,11 @@ func TestCatWrapper_MultipleFlags(t *testing.T) {
 	var calledOptions cat.Options
 
 	// stub internal implementation
-	old := cat.CatFunc
-	cat.CatFunc = func(filenames []string, opts cat.Options) error {
+	app := NewApp()
+	app.Cat = func(filenames []string, opts cat.Options) error {
 		calledOptions = opts
 		return nil
 	}
-	defer func() { cat.CatFunc = old }()
 
 	// Create command with multiple flags enabled
 	cmd := &cobra.Command{}
@@ -276,7 +270,7 @@ func TestCatWrapper_MultipleFlags(t *testing.T) {
 
 	// Execute
 	args := []string{"test.txt"}
-	if err := catCmd.RunE(cmd, args); err != nil {
+	if err := newCatCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("cat RunE failed: %v", err)
 	}
 
@@ -0,0 +1,915 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/rising3/go-cli/internal/cmd/backup"
+	"github.com/rising3/go-cli/internal/cmd/bundle"
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/migrate"
+	profilecmd "github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/configfile"
+	"github.com/rising3/go-cli/internal/fileperm"
+	"github.com/rising3/go-cli/internal/schema"
+	"github.com/rising3/go-cli/internal/secret"
+	"github.com/rising3/go-cli/internal/stdio"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+func newConfigCommand(app *App) *cobra.Command {
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Read and modify values in a profile config file",
+		Long: `Read and modify individual values of the active profile's config file
+without opening an editor. Keys are dotted paths such as common.var2.
+
+The profile is selected with --profile, MYCLI_PROFILE or "profile use" and
+defaults to "default".`,
+	}
+
+	configCmd.AddCommand(newConfigGetCommand(app))
+	configCmd.AddCommand(newConfigSetCommand(app))
+	configCmd.AddCommand(newConfigUnsetCommand(app))
+	configCmd.AddCommand(newConfigListCommand(app))
+	configCmd.AddCommand(newConfigValidateCommand(app))
+	configCmd.AddCommand(newConfigShowCommand(app))
+	configCmd.AddCommand(newConfigDiffCommand(app))
+	configCmd.AddCommand(newConfigWatchCommand(app))
+	configCmd.AddCommand(newConfigMigrateCommand(app))
+	configCmd.AddCommand(newConfigSetSecretCommand(app))
+	configCmd.AddCommand(newConfigRotateKeyCommand(app))
+	configCmd.AddCommand(newConfigExportCommand(app))
+	configCmd.AddCommand(newConfigImportCommand(app))
+	configCmd.AddCommand(newConfigFixPermissionsCommand(app))
+	configCmd.AddCommand(newConfigBackupsCommand(app))
+	configCmd.AddCommand(newConfigRestoreCommand(app))
+	return configCmd
+}
+
+func newConfigGetCommand(app *App) *cobra.Command {
+	configGetCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the value of a key",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, data, err := app.readProfileSettings(app.activeProfile())
+			if err != nil {
+				return err
+			}
+			return config.Get(data, args[0], configOptions(cmd))
+		},
+	}
+	return configGetCmd
+}
+
+func newConfigSetCommand(app *App) *cobra.Command {
+	configSetCmd := &cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set the value of a key",
+		Long: `Set the value of a key in the active profile's config file.
+The value is converted to the type of the corresponding Config field.`,
+		Example: `  mycli config set common.var1 hello
+  mycli --profile prod config set common.var2 42`,
+		Args:        cobra.ExactArgs(2),
+		Annotations: map[string]string{allowMissingProfileAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := strings.ToLower(args[0])
+			value, err := coerceConfigValue(key, args[1])
+			if err != nil {
+				return err
+			}
+
+			return app.updateProfileSettings(app.activeProfile(), func(target string, data map[string]interface{}) error {
+				if key == ExtendsKey {
+					if err := app.checkExtends(app.activeProfile(), args[1]); err != nil {
+						return err
+					}
+				}
+				config.SetValue(data, key, value)
+				if err := configure.WriteFile(target, data, configure.FormatFromPath(target)); err != nil {
+					return err
+				}
+				cmd.PrintErrln("Updated", key, "in", target)
+				return nil
+			})
+		},
+	}
+	return configSetCmd
+}
+
+func newConfigUnsetCommand(app *App) *cobra.Command {
+	configUnsetCmd := &cobra.Command{
+		Use:         "unset <key>",
+		Short:       "Remove a key",
+		Args:        cobra.ExactArgs(1),
+		Annotations: map[string]string{allowMissingProfileAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return app.updateProfileSettings(app.activeProfile(), func(target string, data map[string]interface{}) error {
+				if !config.UnsetValue(data, args[0]) {
+					return fmt.Errorf("key not found: %s", args[0])
+				}
+				if err := configure.WriteFile(target, data, configure.FormatFromPath(target)); err != nil {
+					return err
+				}
+				cmd.PrintErrln("Removed", strings.ToLower(args[0]), "from", target)
+				return nil
+			})
+		},
+	}
+	return configUnsetCmd
+}
+
+func newConfigListCommand(app *App) *cobra.Command {
+	configListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List all keys and values",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, data, err := app.readProfileSettings(app.activeProfile())
+			if err != nil {
+				return err
+			}
+			return config.List(data, configOptions(cmd))
+		},
+	}
+	return configListCmd
+}
+
+func newConfigValidateCommand(app *App) *cobra.Command {
+	configValidateCmd := &cobra.Command{
+		Use:   "validate",
+		Short: "Validate the effective configuration",
+		Long: `Validate the effective configuration of the active profile against the
+rules declared on the Config struct, after ${...} placeholders are expanded,
+secret references resolved and secrets decrypted. Every violation is reported
+with where the offending value is set: the file, line and column, or the flag
+or environment variable. The command exits non-zero if any violation is found.`,
+		Example: `  mycli config validate
+  mycli --profile prod config validate`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		Annotations:  map[string]string{resolveReferencesAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			violations, err := schema.ValidateRedacted(app.Config, app.isSensitive)
+			if err != nil {
+				return err
+			}
+			return config.Validate(violations, app.valueLocation, configOptions(cmd))
+		},
+	}
+	return configValidateCmd
+}
+
+func newConfigShowCommand(app *App) *cobra.Command {
+	var configShowOrigin bool
+	var configShowOutput string
+	var configShowRaw bool
+
+	configShowCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the effective configuration",
+		Long: `Show the effective configuration after merging default.yaml, the active
+profile chain, an explicit --config file, MYCLI_* environment variables and
+configuration flags such as --common.var1.
+
+Values are shown with ${...} placeholders expanded and secret references
+resolved; --raw shows them as written instead.
+
+With --origin, every key is printed together with the layer it came from
+(default, profile:<name>, config, env, flag or unset) and the file path,
+environment variable or flag name. Secret values are always masked.`,
+		Example: `  mycli config show
+  mycli --profile prod config show --origin --output table`,
+		Args:        cobra.NoArgs,
+		Annotations: map[string]string{resolveReferencesAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			entries := app.effectiveEntries(app.Config)
+			if configShowRaw {
+				entries = app.rawEntries()
+			}
+			return config.Show(entries, config.ShowOptions{
+				Origin: configShowOrigin,
+				Format: configShowOutput,
+				Output: cmd.OutOrStdout(),
+			})
+		},
+	}
+
+	configShowCmd.Flags().BoolVar(&configShowOrigin, "origin", false, "show the source layer and path of every value")
+	configShowCmd.Flags().StringVarP(&configShowOutput, "output", "o", "yaml", "output format: yaml, json or table")
+	configShowCmd.Flags().BoolVar(&configShowRaw, "raw", false, "show merged values before interpolation and secret resolution")
+	return configShowCmd
+}
+
+func newConfigDiffCommand(app *App) *cobra.Command {
+	var configDiffOutput string
+	var configDiffShowSecrets bool
+
+	configDiffCmd := &cobra.Command{
+		Use:   "diff <a> <b>",
+		Short: "Show the keys that differ between two configurations",
+		Long: `Compare two configurations key by key and print every key added, removed
+or changed from <a> to <b>. Each side is one of:
+
+  <profile>            the profile's own config file, without inheritance
+  <path>               a config file, recognized by a path separator or a
+                       config file extension
+  effective:<profile>  the configuration the profile loads, after merging
+                       the default, extended and project files and applying
+                       MYCLI_* environment variables
+
+Values of secret fields, and values resolved from secret references, are
+masked unless --show-secrets is given.`,
+		Example: `  mycli config diff dev prod
+  mycli config diff effective:dev effective:prod --output json
+  mycli config diff prod ./prod.backup.yaml`,
+		Args:         cobra.ExactArgs(2),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			a, aRefs, err := app.diffSide(args[0])
+			if err != nil {
+				return err
+			}
+			b, bRefs, err := app.diffSide(args[1])
+			if err != nil {
+				return err
+			}
+
+			opts := config.DiffOptions{
+				AName:  args[0],
+				BName:  args[1],
+				Format: configDiffOutput,
+				Output: cmd.OutOrStdout(),
+			}
+			if !configDiffShowSecrets {
+				opts.Secret = func(key string) bool {
+					field, ok := schema.Lookup(Config{}, key)
+					return (ok && field.Secret()) || aRefs[key] || bRefs[key]
+				}
+			}
+			return config.Diff(a, b, opts)
+		},
+	}
+
+	configDiffCmd.Flags().StringVarP(&configDiffOutput, "output", "o", "text", "output format: text or json")
+	configDiffCmd.Flags().BoolVar(&configDiffShowSecrets, "show-secrets", false, "show the values of secret keys")
+	return configDiffCmd
+}
+
+func newConfigWatchCommand(app *App) *cobra.Command {
+	var configWatchOutput string
+
+	configWatchCmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Print the effective configuration whenever it changes",
+		Long: `Watch the default config file and the active profile chain (or the file
+given with --config) and print the effective configuration every time an edit
+is reloaded. Edits that cannot be read, contain unknown keys in strict mode or
+violate the Config rules are reported and the previous configuration is kept.
+
+Long-running commands get the same behavior through WatchConfig, CurrentConfig
+and SubscribeConfig. Stop watching with Ctrl-C.`,
+		Args:         cobra.NoArgs,
+		Annotations:  map[string]string{resolveReferencesAnnotation: "true"},
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts := config.ShowOptions{Format: configWatchOutput, Output: cmd.OutOrStdout()}
+			if err := config.Show(app.effectiveEntries(app.CurrentConfig()), opts); err != nil {
+				return err
+			}
+
+			unsubscribe := app.SubscribeConfig(func(cfg Config) {
+				cmd.PrintErrln("Reloaded configuration")
+				if err := config.Show(app.effectiveEntries(cfg), opts); err != nil {
+					cmd.PrintErrln(err)
+				}
+			})
+			defer unsubscribe()
+
+			ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt)
+			defer stop()
+			return app.WatchConfig(ctx, func(err error) { cmd.PrintErrln(err) })
+		},
+	}
+
+	configWatchCmd.Flags().StringVarP(&configWatchOutput, "output", "o", "yaml", "output format: yaml, json or table")
+	return configWatchCmd
+}
+
+func newConfigMigrateCommand(app *App) *cobra.Command {
+	var configMigrateAll bool
+	var configMigrateDryRun bool
+
+	configMigrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Upgrade config files to the current schema version",
+		Long: `Upgrade config files written for an older schema version by applying the
+pending migration steps, and print a unified diff of every change. The
+original file is kept next to the migrated one with a .bak suffix.
+
+The active profile (or the file given with --config) is migrated unless
+--all is given, in which case every profile in the config directory is.`,
+		Example: `  mycli config migrate --dry-run
+  mycli config migrate --profile prod
+  mycli config migrate --all`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			paths, err := app.configTargets(configMigrateAll)
+			if err != nil {
+				return err
+			}
+			opts := migrateOptions(cmd)
+			opts.DryRun = configMigrateDryRun
+
+			var errs []error
+			for _, path := range paths {
+				err := configure.WithLock(path, func() error {
+					vp, err := readConfigFile(path)
+					if err != nil {
+						return err
+					}
+					return migrate.File(path, vp.AllSettings(), opts)
+				})
+				if err != nil {
+					errs = append(errs, err)
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	configMigrateCmd.Flags().BoolVar(&configMigrateAll, "all", false, "migrate every profile")
+	configMigrateCmd.Flags().BoolVar(&configMigrateDryRun, "dry-run", false, "print the diff without writing any file")
+	return configMigrateCmd
+}
+
+// configTargets returns the config files a command operates on: every
+// profile when all is set, otherwise the --config file or the active profile.
+func (a *App) configTargets(all bool) ([]string, error) {
+	if all {
+		opts := a.profileOptions(nil)
+		names, err := profilecmd.Names(opts)
+		if err != nil {
+			return nil, err
+		}
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = profilecmd.Path(name, opts)
+		}
+		return paths, nil
+	}
+	if a.cfgFile != "" {
+		return []string{a.cfgFile}, nil
+	}
+	path, ok := a.findConfigFile(a.activeProfile())
+	if !ok {
+		return nil, fmt.Errorf("profile not found: %s", a.activeProfile())
+	}
+	return []string{path}, nil
+}
+
+func newConfigSetSecretCommand(app *App) *cobra.Command {
+	configSetSecretCmd := &cobra.Command{
+		Use:   "set-secret <key> [value]",
+		Short: "Encrypt and store the value of a secret key",
+		Long: `Encrypt the value of a key marked as secret (such as client-secret) and
+store it in the active profile's config file as an "enc:" envelope. The value
+is read from standard input when it is not given as an argument, which keeps
+it out of the shell history.
+
+The encryption key is derived from the passphrase in ` + PassphraseEnv + `, which
+must also be set when the configuration is loaded.
+
+The file is not backed up, and its existing backups are removed, as they may
+hold the secret in plaintext.`,
+		Example: `  MYCLI_PASSPHRASE=... mycli config set-secret client-secret
+  MYCLI_PASSPHRASE=... mycli --profile prod config set-secret client-secret s3cr3t`,
+		Args:         cobra.RangeArgs(1, 2),
+		SilenceUsage: true,
+		Annotations:  map[string]string{allowMissingProfileAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key := strings.ToLower(args[0])
+			if field, ok := schema.Lookup(Config{}, key); !ok || !field.Secret() {
+				return fmt.Errorf("not a secret configuration key: %s", key)
+			}
+			passphrase := os.Getenv(PassphraseEnv)
+			if passphrase == "" {
+				return fmt.Errorf("%s must be set to encrypt secrets", PassphraseEnv)
+			}
+
+			var value string
+			if len(args) == 2 {
+				value = args[1]
+			} else {
+				line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+				if err != nil && !errors.Is(err, io.EOF) {
+					return err
+				}
+				value = strings.TrimRight(line, "\r\n")
+			}
+
+			return app.updateProfileSettings(app.activeProfile(), func(target string, data map[string]interface{}) error {
+				if err := config.SetSecret(data, key, value, passphrase); err != nil {
+					return err
+				}
+				if err := writeSecretFile(cmd, target, data); err != nil {
+					return err
+				}
+				cmd.PrintErrln("Encrypted", key, "in", target)
+				return nil
+			})
+		},
+	}
+	return configSetSecretCmd
+}
+
+func newConfigRotateKeyCommand(app *App) *cobra.Command {
+	var configRotateKeyAll bool
+
+	configRotateKeyCmd := &cobra.Command{
+		Use:   "rotate-key",
+		Short: "Re-encrypt secrets with a new passphrase",
+		Long: `Decrypt every encrypted value of the active profile (or of every profile
+with --all) with the passphrase in ` + PassphraseEnv + ` and encrypt it again with
+the one in ` + NewPassphraseEnv + `. No file is written unless every value can be
+decrypted. Rotated files are not backed up, and their existing backups are
+removed, as they hold the secrets encrypted with the old passphrase.`,
+		Example:      `  MYCLI_PASSPHRASE=old MYCLI_NEW_PASSPHRASE=new mycli config rotate-key --all`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			oldPassphrase, newPassphrase := os.Getenv(PassphraseEnv), os.Getenv(NewPassphraseEnv)
+			if oldPassphrase == "" || newPassphrase == "" {
+				return fmt.Errorf("%s and %s must be set to rotate the key", PassphraseEnv, NewPassphraseEnv)
+			}
+			paths, err := app.configTargets(configRotateKeyAll)
+			if err != nil {
+				return err
+			}
+			for _, path := range paths {
+				unlock, err := configure.Lock(path)
+				if err != nil {
+					return err
+				}
+				defer unlock()
+			}
+
+			rotated := make([]map[string]interface{}, len(paths))
+			counts := make([]int, len(paths))
+			for i, path := range paths {
+				vp, err := readConfigFile(path)
+				if err != nil {
+					return err
+				}
+				rotated[i] = vp.AllSettings()
+				keys, err := config.RotateKeys(rotated[i], oldPassphrase, newPassphrase)
+				if err != nil {
+					return fmt.Errorf("%s: %w", path, err)
+				}
+				counts[i] = len(keys)
+			}
+
+			for i, path := range paths {
+				if counts[i] == 0 {
+					continue
+				}
+				stampSchemaVersion(rotated[i])
+				if err := writeSecretFile(cmd, path, rotated[i]); err != nil {
+					return err
+				}
+				cmd.PrintErrf("Rotated %d secret(s) in %s\n", counts[i], path)
+			}
+			return nil
+		},
+	}
+
+	configRotateKeyCmd.Flags().BoolVar(&configRotateKeyAll, "all", false, "rotate the secrets of every profile")
+	return configRotateKeyCmd
+}
+
+func newConfigExportCommand(app *App) *cobra.Command {
+	var configExportProfiles []string
+	var configExportRedact bool
+	var configExportOutput string
+
+	configExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Pack profile files into a portable bundle",
+		Long: `Write every profile (or the ones given with --profiles) to a single
+tar.gz bundle together with a manifest recording the CLI and schema versions.
+The bundle is written to standard output unless --output names a file.
+
+Secret fields are encrypted with the passphrase in ` + PassphraseEnv + ` when they
+are stored in plain text; values that are already encrypted or that reference
+an env:, file: or exec: source are kept as is. With --redact, secret values
+are left out of the bundle instead.`,
+		Example: `  mycli config export --redact -o team.tar.gz
+  MYCLI_PASSPHRASE=... mycli config export --profiles dev,prod -o backup.tar.gz`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := cmd.OutOrStdout()
+			if configExportOutput != "-" {
+				fw, closer, err := stdio.OpenWriterWithPerm(configExportOutput, fileperm.FileMode)
+				if err != nil {
+					return err
+				}
+				defer stdio.CloseAll(closer)
+				w = fw
+			}
+
+			err := bundle.Export(bundle.ExportOptions{
+				Profile:    app.profileOptions(cmd),
+				Profiles:   configExportProfiles,
+				Keys:       append(schema.Keys(Config{}), reservedKeys...),
+				Secrets:    secretKeys(),
+				Redact:     configExportRedact,
+				Passphrase: os.Getenv(PassphraseEnv),
+				Manifest:   bundle.Manifest{CLI: CliName, CLIVersion: CliVersion, SchemaVersion: SchemaVersion()},
+				Output:     w,
+			})
+			if errors.Is(err, bundle.ErrPlaintextSecret) {
+				return fmt.Errorf("%w; set %s to encrypt it or use --redact", err, PassphraseEnv)
+			}
+			if err == nil && configExportOutput != "-" {
+				cmd.PrintErrln("Exported bundle:", configExportOutput)
+			}
+			return err
+		},
+	}
+
+	configExportCmd.Flags().StringSliceVar(&configExportProfiles, "profiles", nil, "comma-separated profiles to export (default all)")
+	configExportCmd.Flags().BoolVar(&configExportRedact, "redact", false, "leave secret values out of the bundle")
+	configExportCmd.Flags().StringVarP(&configExportOutput, "output", "o", "-", `bundle file to write ("-" for standard output)`)
+	return configExportCmd
+}
+
+func newConfigImportCommand(app *App) *cobra.Command {
+	var configImportMerge bool
+	var configImportReplace bool
+
+	configImportCmd := &cobra.Command{
+		Use:   "import <bundle>",
+		Short: "Restore profile files from a bundle",
+		Long: `Restore the profiles of a bundle written by "config export" ("-" reads it
+from standard input).
+
+By default nothing is written when a profile of the bundle already exists;
+the conflicting profiles are listed instead. With --merge, keys missing from
+an existing profile are added and every key whose local value differs is
+reported and kept. With --replace, existing profiles are overwritten.
+
+Encrypted secrets are imported as is and need the same ` + PassphraseEnv + `;
+secrets redacted on export are listed so that they can be set again. Bundles
+exported for a newer schema-version are rejected.`,
+		Example: `  mycli config import team.tar.gz
+  mycli config import --merge team.tar.gz`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		Annotations:  map[string]string{allowMissingProfileAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			r := cmd.InOrStdin()
+			if args[0] != "-" {
+				fr, closer, err := stdio.OpenReader(args[0])
+				if err != nil {
+					return err
+				}
+				defer stdio.CloseAll(closer)
+				r = fr
+			}
+
+			opts := bundle.ImportOptions{
+				Profile:       app.profileOptions(cmd),
+				Keys:          append(schema.Keys(Config{}), reservedKeys...),
+				CLI:           CliName,
+				SchemaVersion: SchemaVersion(),
+				Output:        cmd.OutOrStdout(),
+				ErrOutput:     cmd.ErrOrStderr(),
+			}
+			switch {
+			case configImportMerge:
+				opts.Mode = bundle.ModeMerge
+			case configImportReplace:
+				opts.Mode = bundle.ModeReplace
+			}
+			return bundle.Import(r, opts)
+		},
+	}
+
+	configImportCmd.Flags().BoolVar(&configImportMerge, "merge", false, "add missing keys to existing profiles and keep local values")
+	configImportCmd.Flags().BoolVar(&configImportReplace, "replace", false, "overwrite existing profiles")
+	configImportCmd.MarkFlagsMutuallyExclusive("merge", "replace")
+	return configImportCmd
+}
+
+func newConfigFixPermissionsCommand(app *App) *cobra.Command {
+	configFixPermissionsCmd := &cobra.Command{
+		Use:   "fix-permissions",
+		Short: "Make the config files private to the current user",
+		Long: `Remove the group and other permissions of the config directory, of every
+file in it and of the --config file, and take ownership of those owned by
+another user (which normally requires root).
+
+The configuration is not loaded, so files rejected with --strict-permissions
+can be repaired.`,
+		Args:         cobra.NoArgs,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := app.resolveEnvOverrides(); err != nil {
+				return err
+			}
+			paths, err := app.permissionTargets()
+			if err != nil {
+				return err
+			}
+			var errs []error
+			for _, path := range paths {
+				fixed, err := fileperm.Fix(path)
+				switch {
+				case err != nil:
+					errs = append(errs, err)
+				case fixed:
+					cmd.PrintErrln("Fixed permissions of", path)
+				}
+			}
+			return errors.Join(errs...)
+		},
+	}
+	return configFixPermissionsCmd
+}
+
+func newConfigBackupsCommand(app *App) *cobra.Command {
+	configBackupsCmd := &cobra.Command{
+		Use:   "backups [profile]",
+		Short: "List the saved versions of a profile",
+		Long: `List the backups of a profile (the active one by default), newest first.
+
+Every command that writes a profile file saves the version it replaces as
+<file>.<version>.bak next to it, keeping the last ` + strconv.Itoa(configfile.Keep) + `; deleting a
+profile saves it as well. Use "config restore" to roll back; both commands
+work even when the profile cannot be loaded.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := app.resolveEnvOverrides(); err != nil {
+				return err
+			}
+			name := app.activeProfile()
+			if len(args) == 1 {
+				name = args[0]
+			}
+			return backup.List(name, app.profileOptions(cmd))
+		},
+	}
+	return configBackupsCmd
+}
+
+func newConfigRestoreCommand(app *App) *cobra.Command {
+	var configRestoreVersion string
+
+	configRestoreCmd := &cobra.Command{
+		Use:   "restore <profile>",
+		Short: "Roll a profile back to a saved version",
+		Long: `Replace the file of a profile with one of its backups: the newest one, or
+the one given with --version (see "config backups"). The version being
+replaced is backed up in turn, so a restore can be undone.`,
+		Example: `  mycli config restore dev
+  mycli config restore dev --version 20250102T150405.000Z`,
+		Args:         cobra.ExactArgs(1),
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return backup.Restore(args[0], configRestoreVersion, app.profileOptions(cmd))
+		},
+	}
+
+	configRestoreCmd.Flags().StringVar(&configRestoreVersion, "version", "", "version to restore (default the newest)")
+	return configRestoreCmd
+}
+
+// secretKeys returns the dotted keys of the Config fields marked as secret.
+func secretKeys() []string {
+	var keys []string
+	for _, f := range schema.Fields(Config{}) {
+		if f.Secret() {
+			keys = append(keys, f.Key)
+		}
+	}
+	return keys
+}
+
+// activeProfile returns the profile selected by --profile, MYCLI_PROFILE or
+// `profile use`, falling back to DefaultProfile.
+func (a *App) activeProfile() string {
+	if a.profile != "" {
+		return a.profile
+	}
+	return DefaultProfile
+}
+
+// coerceConfigValue converts raw to the type of the Config field addressed by key.
+// Profile-level keys such as ExtendsKey are kept as strings.
+func coerceConfigValue(key, raw string) (interface{}, error) {
+	switch key {
+	case ExtendsKey:
+		return raw, nil
+	case StrictConfigKey, StrictPermissionsKey:
+		return strconv.ParseBool(raw)
+	}
+	field, ok := schema.Lookup(Config{}, key)
+	if !ok {
+		return nil, fmt.Errorf("unknown configuration key: %s", key)
+	}
+	return schema.Coerce(field, raw)
+}
+
+// effectiveEntries returns every Config key with its effective value from
+// c and its origin. Values of secret fields, values resolved from secret
+// references and values interpolating either are masked (see isSensitive).
+func (a *App) effectiveEntries(c Config) []config.Entry {
+	cfg := reflect.ValueOf(c)
+	var entries []config.Entry
+	for _, f := range schema.Fields(Config{}) {
+		value := cfg.FieldByIndex(f.Index)
+		entry := config.Entry{Key: f.Key, Value: value.Interface()}
+		if a.isSensitive(f.Key) && !value.IsZero() {
+			entry.Value = config.Mask
+		}
+		entry.Source, entry.Path = a.configOrigin(f.Key)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// rawEntries returns every Config key with its merged value as written,
+// before ${...} interpolation, reference resolution and decryption, and its
+// origin. Secret fields are masked unless they hold a reference or an
+// encrypted envelope.
+func (a *App) rawEntries() []config.Entry {
+	var entries []config.Entry
+	for _, f := range schema.Fields(Config{}) {
+		entry := config.Entry{Key: f.Key, Value: a.viper.Get(f.Key)}
+		raw := a.viper.GetString(f.Key)
+		if f.Secret() && raw != "" && !secret.IsReference(raw) && !secret.IsEncrypted(raw) {
+			entry.Value = config.Mask
+		}
+		entry.Source, entry.Path = a.configOrigin(f.Key)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// effectivePrefix selects the effective configuration of a profile as a
+// side of `config diff`.
+const effectivePrefix = "effective:"
+
+// diffSide reads a side of `config diff`: the effective configuration of a
+// profile, a config file or a profile's own file. It also returns the keys
+// whose effective value is masked beyond the secret fields: values resolved
+// from secret references or interpolating a secret (see isSensitive).
+func (a *App) diffSide(arg string) (map[string]interface{}, map[string]bool, error) {
+	if name, ok := strings.CutPrefix(arg, effectivePrefix); ok {
+		if name == "" {
+			name = a.activeProfile()
+		}
+		if err := profilecmd.ValidateName(name); err != nil {
+			return nil, nil, err
+		}
+		return a.effectiveSettings(name)
+	}
+
+	path := arg
+	if !strings.ContainsRune(arg, '/') && !strings.ContainsRune(arg, filepath.Separator) && configure.FormatFromPath(arg) == "" {
+		if err := profilecmd.ValidateName(arg); err != nil {
+			return nil, nil, err
+		}
+		found, ok := a.findConfigFile(arg)
+		if !ok {
+			return nil, nil, &ConfigError{Kind: ProfileNotFound, Err: fmt.Errorf("profile %q does not exist", arg)}
+		}
+		path = found
+	}
+	vp, err := readConfigFile(path)
+	if err != nil {
+		return nil, nil, configReadError(path, err)
+	}
+	return vp.AllSettings(), nil, nil
+}
+
+// effectiveSettings loads the given profile as --profile would, with the
+// app's config directories and MYCLI_* environment variables but without
+// --config or configuration flags, and returns every Config key with its
+// effective value together with the sensitive keys (see diffSide). The app's
+// own configuration is left unchanged.
+func (a *App) effectiveSettings(name string) (map[string]interface{}, map[string]bool, error) {
+	sub := NewApp()
+	sub.Streams = a.Streams
+	sub.ConfigDir = a.configDir()
+	sub.SystemConfigDirs = a.systemConfigDirs()
+	sub.ExecCommand = a.ExecCommand
+	sub.profile = name
+	sub.resolveRefs = true
+	// Bind every key, as NewRootCommand does, so that environment variables
+	// apply to keys no config file sets.
+	if err := addConfigFlags(pflag.NewFlagSet(name, pflag.ContinueOnError), sub.viper); err != nil {
+		return nil, nil, err
+	}
+	if err := sub.readDefaultAndMergeProfile(false); err != nil {
+		return nil, nil, err
+	}
+	cfg, _, err := sub.decodeConfig()
+	if err != nil {
+		return nil, nil, &ConfigError{Kind: ConfigInvalid, Err: fmt.Errorf("%s%s: %w", effectivePrefix, name, err)}
+	}
+
+	settings := map[string]interface{}{}
+	refs := map[string]bool{}
+	v := reflect.ValueOf(cfg)
+	for _, f := range schema.Fields(Config{}) {
+		config.SetValue(settings, f.Key, v.FieldByIndex(f.Index).Interface())
+		refs[f.Key] = sub.isSensitive(f.Key)
+	}
+	return settings, refs, nil
+}
+
+// writeSecretFile replaces the config file at path with data, in the format
+// of its extension, without backing it up, and removes its existing backups,
+// including the one kept by config migrate. set-secret and rotate-key write
+// with it: the versions they replace hold the plaintext secret or the
+// secrets encrypted with the old passphrase.
+func writeSecretFile(cmd *cobra.Command, path string, data map[string]interface{}) error {
+	out, err := configure.Marshal(data, configure.FormatFromPath(path))
+	if err != nil {
+		return err
+	}
+	if err := configfile.WriteAtomic(path, out); err != nil {
+		return err
+	}
+	n, err := configfile.RemoveBackups(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path + migrate.BackupSuffix); err == nil {
+		n++
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if n > 0 {
+		cmd.PrintErrf("Removed %d backup(s) of %s holding the previous secrets\n", n, path)
+	}
+	return nil
+}
+
+// updateProfileSettings locks the config file of the given profile, reads its
+// settings (see readProfileSettings), stamps them with the current schema
+// version (see stampSchemaVersion) and passes them to update, which may write
+// them back before the lock is released.
+func (a *App) updateProfileSettings(name string, update func(target string, data map[string]interface{}) error) error {
+	target, ok := a.findConfigFile(name)
+	if !ok {
+		target = filepath.Join(a.configDir(), GetConfigFile(name))
+	}
+	return configure.WithLock(target, func() error {
+		target, data, err := a.readProfileSettings(name)
+		if err != nil {
+			return err
+		}
+		stampSchemaVersion(data)
+		return update(target, data)
+	})
+}
+
+// readProfileSettings reads the config file of the given profile through viper.
+// It returns the file path and its settings; a missing file yields the default
+// path for the profile and an empty map.
+func (a *App) readProfileSettings(name string) (string, map[string]interface{}, error) {
+	vp := a.newProfileViper(name)
+	if err := vp.ReadInConfig(); err != nil {
+		var notFound viper.ConfigFileNotFoundError
+		if errors.As(err, &notFound) {
+			return filepath.Join(a.configDir(), GetConfigFile(name)), map[string]interface{}{}, nil
+		}
+		return "", nil, err
+	}
+	return vp.ConfigFileUsed(), vp.AllSettings(), nil
+}
+
+// configOptions builds config.Options bound to the command's streams.
+func configOptions(cmd *cobra.Command) config.Options {
+	return config.Options{
+		Output:    cmd.OutOrStdout(),
+		ErrOutput: cmd.ErrOrStderr(),
+	}
+}
@@ -0,0 +1,448 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/configfile"
+	"github.com/rising3/go-cli/internal/secret"
+	"github.com/spf13/cobra"
+)
+
+// runConfigCmd runs the RunE of c with buffered streams and returns stdout.
+func runConfigCmd(t *testing.T, c *cobra.Command, args ...string) (string, error) {
+	t.Helper()
+	var out, errOut bytes.Buffer
+	wrapper := &cobra.Command{}
+	wrapper.SetOut(&out)
+	wrapper.SetErr(&errOut)
+	err := c.RunE(wrapper, args)
+	return out.String(), err
+}
+
+func TestConfigSetGetUnset(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	app := NewApp()
+	app.profile = "dev"
+
+	if _, err := runConfigCmd(t, newConfigSetCommand(app), "common.var2", "5"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if _, err := runConfigCmd(t, newConfigSetCommand(app), "common.var1", "hello"); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(GetConfigPath(), GetConfigFile("dev")))
+	if err != nil {
+		t.Fatalf("expected profile file to be written: %v", err)
+	}
+	if !strings.Contains(string(b), "var2: 5\n") {
+		t.Errorf("expected var2 written as int, got:\n%s", b)
+	}
+	if !strings.Contains(string(b), "schema-version: 1\n") {
+		t.Errorf("expected the new file to record its schema version, got:\n%s", b)
+	}
+
+	out, err := runConfigCmd(t, newConfigGetCommand(app), "common.var1")
+	if err != nil {
+		t.Fatalf("config get failed: %v", err)
+	}
+	if out != "hello\n" {
+		t.Errorf("get output = %q, want %q", out, "hello\n")
+	}
+
+	if _, err := runConfigCmd(t, newConfigUnsetCommand(app), "common.var1"); err != nil {
+		t.Fatalf("config unset failed: %v", err)
+	}
+	out, err = runConfigCmd(t, newConfigListCommand(app))
+	if err != nil {
+		t.Fatalf("config list failed: %v", err)
+	}
+	if want := "common.var2=5\nschema-version=1\n"; out != want {
+		t.Errorf("list output = %q, want %q", out, want)
+	}
+}
+
+func TestConfigSet_RejectsInvalidValues(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+
+	app := NewApp()
+
+	if _, err := runConfigCmd(t, newConfigSetCommand(app), "common.var2", "abc"); err == nil {
+		t.Error("expected error when setting non-integer value for common.var2")
+	}
+	if _, err := runConfigCmd(t, newConfigSetCommand(app), "comon.var1", "x"); err == nil {
+		t.Error("expected error for unknown key")
+	}
+	if _, err := os.Stat(filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))); !os.IsNotExist(err) {
+		t.Error("expected no file to be written for rejected values")
+	}
+}
+
+func TestConfigSet_ChecksExtends(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfileFile(t, "staging", "extends: prod\n")
+	writeProfileFile(t, "prod", "client-id: prod\n")
+
+	app := NewApp()
+	app.profile = "prod"
+	for _, parent := range []string{"staging", "prod", "missing", "../base"} {
+		if _, err := runConfigCmd(t, newConfigSetCommand(app), "extends", parent); err == nil {
+			t.Errorf("expected extends %q to be rejected", parent)
+		}
+	}
+	if b, _ := os.ReadFile(filepath.Join(GetConfigPath(), GetConfigFile("prod"))); string(b) != "client-id: prod\n" {
+		t.Errorf("expected prod to be unchanged, got %q", b)
+	}
+
+	app.profile = "dev"
+	for _, parent := range []string{"staging", DefaultProfile} {
+		if _, err := runConfigCmd(t, newConfigSetCommand(app), "extends", parent); err != nil {
+			t.Errorf("extends %q: %v", parent, err)
+		}
+	}
+}
+
+// TestRepairCommands_BrokenChain verifies that a profile whose extends
+// chain is a cycle can be repaired with the profile and config set/unset
+// commands, which do not load the configuration.
+func TestRepairCommands_BrokenChain(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "prod")
+	dir := t.TempDir()
+	for name, content := range map[string]string{"prod": "extends: staging\n", "staging": "extends: prod\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	run := func(args ...string) error {
+		app, _ := newTestApp(t, dir)
+		root := NewRootCommand(app)
+		root.SetArgs(args)
+		return root.Execute()
+	}
+	if err := run("config", "show"); ExitCode(err) != ExitConfigInvalid {
+		t.Fatalf("expected the cycle to be rejected, got %v", err)
+	}
+	for _, args := range [][]string{{"profile", "list"}, {"config", "unset", "extends"}, {"config", "show"}} {
+		if err := run(args...); err != nil {
+			t.Errorf("%v: %v", args, err)
+		}
+	}
+}
+
+func TestConfigMigrate(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	migrateCmd := newConfigMigrateCommand(NewApp())
+	if err := migrateCmd.Flags().Set("all", "true"); err != nil {
+		t.Fatal(err)
+	}
+
+	writeProfileFile(t, DefaultProfile, "client-id: a\n")
+	writeProfileFile(t, "prod", "client-id: b\n")
+
+	out, err := runConfigCmd(t, migrateCmd)
+	if err != nil {
+		t.Fatalf("config migrate failed: %v", err)
+	}
+	if strings.Count(out, "+schema-version: 1\n") != 2 {
+		t.Errorf("expected a diff per profile, got:\n%s", out)
+	}
+	for _, name := range []string{DefaultProfile, "prod"} {
+		path := filepath.Join(GetConfigPath(), GetConfigFile(name))
+		vp, err := readConfigFile(path)
+		if err != nil || vp.GetInt(SchemaVersionKey) != SchemaVersion() {
+			t.Errorf("%s not migrated: %v", name, err)
+		}
+		if _, err := os.Stat(path + ".bak"); err != nil {
+			t.Errorf("expected backup of %s: %v", name, err)
+		}
+	}
+}
+
+// TestConfigValidate verifies that the decoded configuration is validated,
+// with every violation reported where its value is set.
+func TestConfigValidate(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "default.yaml")
+	if err := os.WriteFile(path, []byte("common:\n  var1: \"\"\nhoge:\n  fuga: ${common.var1}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) string {
+		app, out := newTestApp(t, dir)
+		root := NewRootCommand(app)
+		root.SetArgs(append([]string{"config", "validate"}, args...))
+		if err := root.Execute(); err == nil {
+			t.Errorf("%v: expected violations", args)
+		}
+		return out.String()
+	}
+	if out := run(); out != path+":4:9: hoge.fuga: is required\n" {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if out := run("--hoge.fuga", ""); out != "--hoge.fuga: hoge.fuga: is required\n" {
+		t.Errorf("unexpected output for a flag: %q", out)
+	}
+	t.Setenv("MYCLI_COMMON_VAR2", "-1")
+	if out := run(); !contains(out, "MYCLI_COMMON_VAR2: common.var2: ") {
+		t.Errorf("unexpected output for an environment variable: %q", out)
+	}
+}
+
+func TestConfigSetSecretAndRotateKey(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	oldIterations := secret.Iterations
+	secret.Iterations = 1000
+	t.Cleanup(func() { secret.Iterations = oldIterations })
+
+	app := NewApp()
+
+	if _, err := runConfigCmd(t, newConfigSetSecretCommand(app), "client-id", "x"); err == nil {
+		t.Error("expected error for a key not marked as secret")
+	}
+	if _, err := runConfigCmd(t, newConfigSetSecretCommand(app), "client-secret", "s3cr3t"); err == nil {
+		t.Errorf("expected error without %s", PassphraseEnv)
+	}
+
+	// A plaintext secret, backed up by the next write.
+	path := filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))
+	writeProfileFile(t, DefaultProfile, "client-secret: s3cr3t\n")
+	if _, err := runConfigCmd(t, newConfigSetCommand(app), "common.var1", "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv(PassphraseEnv, "old")
+	if _, err := runConfigCmd(t, newConfigSetSecretCommand(app), "client-secret", "s3cr3t"); err != nil {
+		t.Fatalf("config set-secret failed: %v", err)
+	}
+	b, _ := os.ReadFile(path)
+	if strings.Contains(string(b), "s3cr3t") || !strings.Contains(string(b), "client-secret: enc:v1:") {
+		t.Fatalf("expected an encrypted envelope, got:\n%s", b)
+	}
+	if backups, err := configfile.Backups(path); err != nil || len(backups) != 0 {
+		t.Errorf("expected the backups holding the plaintext to be removed, got %v, %v", backups, err)
+	}
+
+	app.resolveRefs = true
+	if err := app.initConfig(false); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+	if app.Config.ClientSecret != "s3cr3t" {
+		t.Errorf("ClientSecret = %q; want decrypted value", app.Config.ClientSecret)
+	}
+
+	// A backup of the old ciphertext.
+	if _, err := runConfigCmd(t, newConfigSetCommand(app), "common.var1", "y"); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(NewPassphraseEnv, "new")
+	if _, err := runConfigCmd(t, newConfigRotateKeyCommand(app)); err != nil {
+		t.Fatalf("config rotate-key failed: %v", err)
+	}
+	if backups, err := configfile.Backups(path); err != nil || len(backups) != 0 {
+		t.Errorf("expected no backup of the old ciphertext, got %v, %v", backups, err)
+	}
+	if err := app.initConfig(false); ExitCode(err) != ExitConfigInvalid {
+		t.Errorf("expected the old passphrase to be rejected, got %v", err)
+	}
+	t.Setenv(PassphraseEnv, "new")
+	if err := app.initConfig(false); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+	if app.Config.ClientSecret != "s3cr3t" {
+		t.Errorf("ClientSecret = %q after rotation", app.Config.ClientSecret)
+	}
+}
+
+func TestConfigDiff(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+	t.Setenv("MYCLI_COMMON_VAR1", "from-env")
+
+	writeProfileFile(t, DefaultProfile, "hoge:\n  fuga: base\n")
+	writeProfileFile(t, "dev", "client-id: dev\nclient-secret: dev-secret\n")
+	writeProfileFile(t, "prod", "client-id: prod\nclient-secret: prod-secret\ncommon:\n  var2: 7\n")
+	app := NewApp()
+
+	out, err := runConfigCmd(t, newConfigDiffCommand(app), "dev", "prod")
+	if err != nil {
+		t.Fatalf("config diff failed: %v", err)
+	}
+	want := "--- dev\n+++ prod\n-client-id: dev\n+client-id: prod\n-client-secret: ********\n+client-secret: ********\n+common.var2: 7\n"
+	if out != want {
+		t.Errorf("profile diff = %q, want %q", out, want)
+	}
+
+	// The effective sides include the default profile and the environment,
+	// which are the same on both sides.
+	diffCmd := newConfigDiffCommand(app)
+	for name, value := range map[string]string{"output": "json", "show-secrets": "true"} {
+		if err := diffCmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	out, err = runConfigCmd(t, diffCmd, "effective:dev", "effective:prod")
+	if err != nil {
+		t.Fatalf("config diff failed: %v", err)
+	}
+	var got struct{ Changes []config.Change }
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	keys := []string{}
+	for _, c := range got.Changes {
+		keys = append(keys, c.Key)
+	}
+	if strings.Join(keys, ",") != "client-id,client-secret,common.var2" || got.Changes[1].New != "prod-secret" {
+		t.Errorf("unexpected effective diff: %s", out)
+	}
+	settings, _, err := app.effectiveSettings("dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := config.GetValue(settings, "common.var1"); v != "from-env" {
+		t.Errorf("effective common.var1 = %v; want from-env", v)
+	}
+	if v, _ := config.GetValue(settings, "hoge.fuga"); v != "base" {
+		t.Errorf("effective hoge.fuga = %v; want base", v)
+	}
+
+	file := filepath.Join(t.TempDir(), "prod.yaml")
+	if err := os.WriteFile(file, []byte("client-id: prod\nclient-secret: prod-secret\ncommon:\n  var2: 8\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runConfigCmd(t, newConfigDiffCommand(app), "prod", file)
+	if err != nil || out != "--- prod\n+++ "+file+"\n-common.var2: 7\n+common.var2: 8\n" {
+		t.Errorf("file diff = %q, %v", out, err)
+	}
+
+	if _, err := runConfigCmd(t, newConfigDiffCommand(app), "dev", "missing"); ExitCode(err) != ExitProfileNotFound {
+		t.Errorf("expected a profile not found error, got %v", err)
+	}
+
+	writeProfileFile(t, "broken", "common:\n  var2: abc\n")
+	if _, err := runConfigCmd(t, newConfigDiffCommand(app), "effective:broken", "dev"); ExitCode(err) != ExitConfigInvalid {
+		t.Errorf("expected an invalid config error, got %v", err)
+	}
+}
+
+// TestConfigDiff_MasksInterpolatedSecrets verifies that effective sides mask
+// values copied from a secret through ${...}.
+func TestConfigDiff_MasksInterpolatedSecrets(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+
+	writeProfileFile(t, DefaultProfile, "hoge:\n  fuga: ${client-secret}\n")
+	writeProfileFile(t, "dev", "client-secret: s1\n")
+	writeProfileFile(t, "prod", "client-secret: s2\n")
+
+	out, err := runConfigCmd(t, newConfigDiffCommand(NewApp()), "effective:dev", "effective:prod")
+	if err != nil {
+		t.Fatalf("config diff failed: %v", err)
+	}
+	if strings.Contains(out, "s1") || strings.Contains(out, "s2") || !strings.Contains(out, "-hoge.fuga: ********\n") {
+		t.Errorf("expected hoge.fuga to be masked, got:\n%s", out)
+	}
+}
+
+func TestConfigExportImport(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv(PassphraseEnv, "")
+	writeProfileFile(t, DefaultProfile, "client-id: a\n")
+	writeProfileFile(t, "dev", "client-id: dev\nclient-secret: s3cr3t\n")
+	app := NewApp()
+
+	if _, err := runConfigCmd(t, newConfigExportCommand(app)); err == nil || !strings.Contains(err.Error(), "--redact") {
+		t.Fatalf("expected a plaintext secret error suggesting --redact, got %v", err)
+	}
+
+	bundlePath := filepath.Join(t.TempDir(), "team.tar.gz")
+	exportCmd := newConfigExportCommand(app)
+	for name, value := range map[string]string{"profiles": "dev", "redact": "true", "output": bundlePath} {
+		if err := exportCmd.Flags().Set(name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := runConfigCmd(t, exportCmd); err != nil {
+		t.Fatalf("config export failed: %v", err)
+	}
+
+	// Import into a fresh config directory, then again into the populated one.
+	t.Setenv("HOME", t.TempDir())
+	app = NewApp()
+	if _, err := runConfigCmd(t, newConfigImportCommand(app), bundlePath); err != nil {
+		t.Fatalf("config import failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(GetConfigPath(), GetConfigFile("dev")))
+	if err != nil || string(b) != "client-id: dev\n" {
+		t.Errorf("imported dev = %q, %v; want the redacted profile", b, err)
+	}
+
+	out, err := runConfigCmd(t, newConfigImportCommand(app), bundlePath)
+	if err == nil || out != "conflict: dev: profile already exists\n" {
+		t.Errorf("expected a conflict, got %q, %v", out, err)
+	}
+	importCmd := newConfigImportCommand(app)
+	if err := importCmd.Flags().Set("merge", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runConfigCmd(t, importCmd, bundlePath); err != nil {
+		t.Errorf("config import --merge failed: %v", err)
+	}
+}
+
+// TestConfigBackupsRestore verifies that configure --force keeps a backup
+// that config restore rolls back to.
+func TestConfigBackupsRestore(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+	writeProfileFile(t, "dev", "client-id: old\n")
+	app := NewApp()
+	app.profile = "dev"
+
+	configureCmd := newConfigureCommand(app)
+	if err := configureCmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runConfigCmd(t, configureCmd); err != nil {
+		t.Fatalf("configure --force failed: %v", err)
+	}
+
+	out, err := runConfigCmd(t, newConfigBackupsCommand(app))
+	if err != nil {
+		t.Fatalf("config backups failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one backup, got:\n%s", out)
+	}
+	version := strings.Fields(lines[1])[0]
+
+	restoreCmd := newConfigRestoreCommand(app)
+	if err := restoreCmd.Flags().Set("version", version); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runConfigCmd(t, restoreCmd, "dev"); err != nil {
+		t.Fatalf("config restore failed: %v", err)
+	}
+	b, err := os.ReadFile(filepath.Join(GetConfigPath(), GetConfigFile("dev")))
+	if err != nil || string(b) != "client-id: old\n" {
+		t.Errorf("restored dev = %q, %v; want the version before configure", b, err)
+	}
+}
@@ -1,50 +1,314 @@
 package cmd
 
 import (
+	"bytes"
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 
+	"github.com/rising3/go-cli/internal/cmd/config"
 	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/configfile"
 	"github.com/rising3/go-cli/internal/editor"
+	"github.com/rising3/go-cli/internal/schema"
+	"github.com/rising3/go-cli/internal/stdio"
 	"github.com/spf13/cobra"
 )
 
-var cfgForce bool
-var cfgEdit bool
-var cfgNoWait bool
+func newConfigureCommand(app *App) *cobra.Command {
+	var cfgForce bool
+	var cfgEdit bool
+	var cfgNoWait bool
+	var cfgFormat string
+	var cfgInteractive bool
+	var cfgSet []string
+	var cfgFromFile string
+	var cfgFromEnv bool
+	var cfgDryRun bool
+	var cfgDiff bool
+	var cfgShowSecrets bool
+
+	configureCmd := &cobra.Command{
+		Use:          "configure",
+		Short:        "Create a scaffold config file based on Config struct",
+		SilenceUsage: true,
+		Annotations:  map[string]string{allowMissingProfileAnnotation: "true"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// T036: Determine target path. Without --format an existing
+			// profile keeps its file and format.
+			dir := app.configDir()
+			existing, found := app.findConfigFile(app.activeProfile())
+			format := cfgFormat
+			var target string
+			if format == "" && found {
+				format, target = configure.FormatFromPath(existing), existing
+			} else {
+				if format == "" {
+					format = CliConfigType
+				}
+				ext, err := configure.Extension(format)
+				if err != nil {
+					return err
+				}
+				target = filepath.Join(dir, app.activeProfile()+"."+ext)
+			}
+
+			// A profile exists in another format: keep it unless --force or
+			// --interactive, in which case it is replaced by the new file.
+			// --dry-run and --diff only preview the new file. Values given
+			// for an existing profile require --force.
+			replaces := found && existing != target
+			force := cfgForce || cfgInteractive
+			preview := cfgDryRun || cfgDiff
+			overlaid := len(cfgSet) > 0 || cfgFromFile != "" || cfgFromEnv
+			if found && overlaid && !force && !preview {
+				return fmt.Errorf("profile %s already exists: pass --force to overwrite it with --set, --from-file or --from-env", app.activeProfile())
+			}
+			if replaces && !force && !preview {
+				cmd.PrintErrln("Config already exists, skipping initialization:", existing)
+				return nil
+			}
+
+			// Overlays in ascending precedence: --from-file, --from-env,
+			// --set and the answers of --interactive
+			overlays, err := configOverlays(cfgFromFile, cfgFromEnv, cfgSet)
+			if err != nil {
+				return err
+			}
+			data := BuildEffectiveConfig()
+			var before []byte
+			if cfgInteractive {
+				// The answers start from the profile as read now; it is read
+				// again under the lock so that a write made meanwhile is not
+				// silently overwritten
+				if found {
+					if before, err = os.ReadFile(existing); err != nil {
+						return err
+					}
+				}
+				streams := stdio.Streams{In: cmd.InOrStdin(), Out: cmd.OutOrStdout(), Err: cmd.ErrOrStderr()}
+				answers, err := app.askConfig(data, found, overlays, streams)
+				if err != nil {
+					return err
+				}
+				overlays = append(overlays, answers)
+			}
+
+			// T037-T039: Build ConfigureOptions
+			opts := configure.ConfigureOptions{
+				Force:            force,
+				Edit:             cfgEdit,
+				NoWait:           cfgNoWait,
+				Data:             data,
+				Overlays:         overlays,
+				Format:           format,
+				DryRun:           cfgDryRun,
+				Diff:             cfgDiff,
+				Input:            cmd.InOrStdin(),
+				Output:           cmd.OutOrStdout(),
+				ErrOutput:        cmd.ErrOrStderr(),
+				EditorLookup:     func() (string, []string, error) { return editor.GetEditor() },
+				EditorShouldWait: func(string, []string) bool { return !cfgNoWait },
+				ExecCommand:      app.ExecCommand,
+			}
+
+			if found {
+				opts.Current = existing
+			}
+			if !cfgShowSecrets {
+				opts.Secret = func(key string) bool {
+					field, ok := schema.Lookup(Config{}, key)
+					return ok && field.Secret()
+				}
+			}
+			// The profile lock taken by Configure also covers checking the
+			// profile read for the answers and removing the file it replaces
+			if cfgInteractive {
+				opts.Check = func() error { return app.checkProfileUnchanged(existing, found, before) }
+			}
+			if replaces {
+				opts.Written = func() error { return configfile.Remove(existing) }
+			}
+
+			// T040: Call internal function
+			return app.Configure(target, opts)
+		},
+	}
 
-func init() {
-	rootCmd.AddCommand(configureCmd)
 	configureCmd.Flags().BoolVar(&cfgForce, "force", false, "overwrite existing config")
 	configureCmd.Flags().BoolVar(&cfgEdit, "edit", false, "edit the created file in $EDITOR")
 	configureCmd.Flags().BoolVar(&cfgNoWait, "no-wait", false, "do not wait for editor to exit")
+	configureCmd.Flags().StringVar(&cfgFormat, "format", "", "file format: "+strings.Join(configure.Formats, ", ")+" (default the existing profile's format, or "+CliConfigType+")")
+	configureCmd.Flags().BoolVar(&cfgInteractive, "interactive", false, "ask for every setting, starting from the current profile")
+	configureCmd.Flags().StringArrayVar(&cfgSet, "set", nil, "set a value as key=value over the scaffold (repeatable)")
+	configureCmd.Flags().StringVar(&cfgFromFile, "from-file", "", "merge the settings of a (partial) config file over the scaffold")
+	configureCmd.Flags().BoolVar(&cfgFromEnv, "from-env", false, "merge the values of the current "+strings.ToUpper(CliName)+"_* environment variables over the scaffold")
+	configureCmd.Flags().BoolVar(&cfgDryRun, "dry-run", false, "print the file to stdout instead of writing it")
+	configureCmd.Flags().BoolVar(&cfgDiff, "diff", false, "print a diff against the existing profile instead of writing the file")
+	configureCmd.Flags().BoolVar(&cfgShowSecrets, "show-secrets", false, "show the values of secret keys in --diff")
+	configureCmd.MarkFlagsMutuallyExclusive("dry-run", "diff")
+	configureCmd.MarkFlagsMutuallyExclusive("dry-run", "edit")
+	configureCmd.MarkFlagsMutuallyExclusive("diff", "edit")
+	return configureCmd
+}
+
+// configOverlays returns the settings of the --from-file file, the
+// --from-env variables and the --set values, in that order, each one
+// checked by checkOverlay.
+func configOverlays(fromFile string, fromEnv bool, set []string) ([]map[string]interface{}, error) {
+	var overlays []map[string]interface{}
+	if fromFile != "" {
+		vp, err := readConfigFile(fromFile)
+		if err != nil {
+			return nil, configReadError(fromFile, err)
+		}
+		settings, err := checkOverlay(fromFile, vp.AllSettings())
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, settings)
+	}
+	if fromEnv {
+		env := map[string]interface{}{}
+		for _, key := range schema.Keys(Config{}) {
+			if v := os.Getenv(configEnvVar(key)); v != "" {
+				config.SetValue(env, key, v)
+			}
+		}
+		settings, err := checkOverlay("environment", env)
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, settings)
+	}
+	if len(set) > 0 {
+		values := map[string]interface{}{}
+		for _, kv := range set {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid --set value %q: expected key=value", kv)
+			}
+			config.SetValue(values, key, value)
+		}
+		settings, err := checkOverlay("--set", values)
+		if err != nil {
+			return nil, err
+		}
+		overlays = append(overlays, settings)
+	}
+	return overlays, nil
+}
+
+// checkOverlay converts every value of settings to the type of its Config
+// field and checks it against the field's validation rules (see
+// fieldParser). The schema version is dropped, since the scaffold records
+// the current one. It returns the converted settings, or an error naming
+// source for the first unknown key or invalid value.
+func checkOverlay(source string, settings map[string]interface{}) (map[string]interface{}, error) {
+	checked := map[string]interface{}{}
+	for _, key := range config.Keys(settings) {
+		if key == SchemaVersionKey {
+			continue
+		}
+		v, _ := config.GetValue(settings, key)
+		if m, ok := v.(map[string]interface{}); ok && len(m) == 0 {
+			continue
+		}
+		raw := config.FormatValue(v)
+		var value interface{}
+		var err error
+		if f, ok := schema.Lookup(Config{}, key); ok {
+			value, err = fieldParser(f)(raw)
+		} else {
+			value, err = coerceConfigValue(key, raw)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", source, err)
+		}
+		config.SetValue(checked, key, value)
+	}
+	return checked, nil
+}
+
+// askConfig walks every Config field with configure.Wizard and returns the
+// answers as an overlay. When exists is set, the settings of the active
+// profile are copied into data first. The current value of a question is
+// the value of data merged with overlays.
+func (a *App) askConfig(data map[string]interface{}, exists bool, overlays []map[string]interface{}, streams stdio.Streams) (map[string]interface{}, error) {
+	if exists {
+		_, current, err := a.readProfileSettings(a.activeProfile())
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range config.Keys(current) {
+			if key == SchemaVersionKey {
+				continue
+			}
+			v, _ := config.GetValue(current, key)
+			config.SetValue(data, key, v)
+		}
+	}
+
+	current := configure.Merge(data, overlays...)
+	var questions []configure.Question
+	for _, f := range schema.Fields(Config{}) {
+		q := configure.Question{Key: f.Key, Description: f.Description(), Secret: f.Secret(), Parse: fieldParser(f)}
+		if v, ok := config.GetValue(current, f.Key); ok {
+			q.Current = config.FormatValue(v)
+		}
+		questions = append(questions, q)
+	}
+	answers, err := configure.Wizard(questions, streams)
+	if err != nil {
+		return nil, err
+	}
+	overlay := map[string]interface{}{}
+	for key, v := range answers {
+		config.SetValue(overlay, key, v)
+	}
+	return overlay, nil
+}
+
+// checkProfileUnchanged returns an error if the active profile's file no
+// longer is existing (found) with the content before, as read before the
+// questions of --interactive were asked.
+func (a *App) checkProfileUnchanged(existing string, found bool, before []byte) error {
+	path, ok := a.findConfigFile(a.activeProfile())
+	changed := ok != found || path != existing
+	if !changed && found {
+		b, err := os.ReadFile(existing)
+		if err != nil {
+			return err
+		}
+		changed = !bytes.Equal(b, before)
+	}
+	if changed {
+		return fmt.Errorf("profile %s was changed while the questions were answered; nothing was written, run configure --interactive again", a.activeProfile())
+	}
+	return nil
 }
 
-var configureCmd = &cobra.Command{
-	Use:   "configure",
-	Short: "Create a scaffold config file based on Config struct",
-	RunE: func(cmd *cobra.Command, args []string) error {
-		// T036: Determine target path
-		dir := GetConfigPath()
-		cfgName := DefaultProfile
-		if profile != "" {
-			cfgName = profile
-		}
-		target := filepath.Join(dir, GetConfigFile(cfgName))
-
-		// T037-T039: Build ConfigureOptions
-		opts := configure.ConfigureOptions{
-			Force:            cfgForce,
-			Edit:             cfgEdit,
-			NoWait:           cfgNoWait,
-			Data:             BuildEffectiveConfig(),
-			Format:           CliConfigType,
-			Output:           cmd.OutOrStdout(),
-			ErrOutput:        cmd.ErrOrStderr(),
-			EditorLookup:     func() (string, []string, error) { return editor.GetEditor() },
-			EditorShouldWait: func(string, []string) bool { return !cfgNoWait },
-		}
-
-		// T040: Call internal function
-		return configure.ConfigureFunc(target, opts)
-	},
+// fieldParser returns a parser converting an answer to the type of f and
+// checking it against the validation rules of f.
+func fieldParser(f schema.Field) func(string) (interface{}, error) {
+	return func(raw string) (interface{}, error) {
+		v, err := schema.Coerce(f, raw)
+		if err != nil {
+			return nil, err
+		}
+		var cfg Config
+		reflect.ValueOf(&cfg).Elem().FieldByIndex(f.Index).Set(reflect.ValueOf(v))
+		violations, err := schema.Validate(cfg)
+		if err != nil {
+			return nil, err
+		}
+		for _, violation := range violations {
+			if violation.Key == f.Key {
+				return nil, fmt.Errorf("%s %s", f.Key, violation.Message)
+			}
+		}
+		return v, nil
+	}
 }
@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strconv"
 	"testing"
 
 	"github.com/rising3/go-cli/internal/cmd/configure"
@@ -14,26 +16,12 @@ func TestConfigureCreatesFile(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	// backup and restore globals
-	oldCliConfig := CliConfig
-	oldCfgForce := cfgForce
-	oldCfgOpen := cfgEdit
-	oldProfile := profile
-	t.Cleanup(func() {
-		CliConfig = oldCliConfig
-		cfgForce = oldCfgForce
-		cfgEdit = oldCfgOpen
-		profile = oldProfile
-	})
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
 	// set config values to scaffold
-	CliConfig.ClientID = "test-id"
-	CliConfig.ClientSecret = "test-secret"
-
-	// ensure profile empty
-	profile = ""
-	cfgForce = false
-	cfgEdit = false
+	app.Config.ClientID = "test-id"
+	app.Config.ClientSecret = "test-secret"
 
 	// run command
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
@@ -71,26 +59,14 @@ func TestConfigureOpenInvokesEditor(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	// backup and restore globals
-	oldCliConfig := CliConfig
-	oldCfgForce := cfgForce
-	oldCfgOpen := cfgEdit
-	oldProfile := profile
-	oldEditor := os.Getenv("EDITOR")
-	t.Cleanup(func() {
-		CliConfig = oldCliConfig
-		cfgForce = oldCfgForce
-		cfgEdit = oldCfgOpen
-		profile = oldProfile
-		if err := os.Setenv("EDITOR", oldEditor); err != nil {
-			t.Fatalf("failed to restore EDITOR: %v", err)
-		}
-	})
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
-	CliConfig.ClientID = "x"
-	CliConfig.ClientSecret = "y"
-	cfgForce = false
-	cfgEdit = true
+	app.Config.ClientID = "x"
+	app.Config.ClientSecret = "y"
+	if err := configureCmd.Flags().Set("edit", "true"); err != nil {
+		t.Fatal(err)
+	}
 
 	// set EDITOR to a no-op that exists on PATH; use 'true'
 	t.Setenv("EDITOR", "true")
@@ -122,23 +98,12 @@ func TestConfigureProfileCreatesProfileFile(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	// backup/restore globals
-	oldCliConfig := CliConfig
-	oldCfgForce := cfgForce
-	oldCfgOpen := cfgEdit
-	oldProfile := profile
-	t.Cleanup(func() {
-		CliConfig = oldCliConfig
-		cfgForce = oldCfgForce
-		cfgEdit = oldCfgOpen
-		profile = oldProfile
-	})
-
-	CliConfig.ClientID = "p-id"
-	CliConfig.ClientSecret = "p-secret"
-	profile = "prod"
-	cfgForce = false
-	cfgEdit = false
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
+
+	app.Config.ClientID = "p-id"
+	app.Config.ClientSecret = "p-secret"
+	app.profile = "prod"
 
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
 		t.Fatalf("configure RunE failed for profile: %v", err)
@@ -155,21 +120,11 @@ func TestConfigureForceOverwrites(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	// backup/restore globals
-	oldCliConfig := CliConfig
-	oldCfgForce := cfgForce
-	oldCfgOpen := cfgEdit
-	oldProfile := profile
-	t.Cleanup(func() {
-		CliConfig = oldCliConfig
-		cfgForce = oldCfgForce
-		cfgEdit = oldCfgOpen
-		profile = oldProfile
-	})
-
-	CliConfig.ClientID = "f-id"
-	CliConfig.ClientSecret = "f-secret"
-	profile = ""
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
+
+	app.Config.ClientID = "f-id"
+	app.Config.ClientSecret = "f-secret"
 
 	cfgDir := GetConfigPath()
 	if err := os.MkdirAll(cfgDir, 0o755); err != nil {
@@ -182,8 +137,6 @@ func TestConfigureForceOverwrites(t *testing.T) {
 		t.Fatalf("write existing: %v", err)
 	}
 
-	cfgForce = false
-	cfgEdit = false
 	// when not forcing, RunE should succeed but not overwrite the existing file
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
 		t.Fatalf("configure RunE failed when file exists and not forcing: %v", err)
@@ -199,7 +152,9 @@ func TestConfigureForceOverwrites(t *testing.T) {
 	}
 
 	// now force overwrite
-	cfgForce = true
+	if err := configureCmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
 		t.Fatalf("configure RunE failed with --force: %v", err)
 	}
@@ -227,14 +182,14 @@ func TestConfigureForceOverwrites(t *testing.T) {
 
 // T030: Test --force flag is passed correctly
 func TestConfigureCommand_ForceFlag(t *testing.T) {
-	// Mock ConfigureFunc
-	oldFunc := configure.ConfigureFunc
-	defer func() { configure.ConfigureFunc = oldFunc }()
+	// Mock app.Configure
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
 	var capturedTarget string
 	var capturedOpts configure.ConfigureOptions
 
-	configure.ConfigureFunc = func(target string, opts configure.ConfigureOptions) error {
+	app.Configure = func(target string, opts configure.ConfigureOptions) error {
 		capturedTarget = target
 		capturedOpts = opts
 		return nil
@@ -244,15 +199,9 @@ func TestConfigureCommand_ForceFlag(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	oldCfgForce := cfgForce
-	oldProfile := profile
-	t.Cleanup(func() {
-		cfgForce = oldCfgForce
-		profile = oldProfile
-	})
-
-	cfgForce = true
-	profile = ""
+	if err := configureCmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
 
 	// Execute
 	cmd := &cobra.Command{}
@@ -274,13 +223,13 @@ func TestConfigureCommand_ForceFlag(t *testing.T) {
 
 // T031: Test --edit flag is passed correctly
 func TestConfigureCommand_EditFlag(t *testing.T) {
-	// Mock ConfigureFunc
-	oldFunc := configure.ConfigureFunc
-	defer func() { configure.ConfigureFunc = oldFunc }()
+	// Mock app.Configure
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
 	var capturedOpts configure.ConfigureOptions
 
-	configure.ConfigureFunc = func(target string, opts configure.ConfigureOptions) error {
+	app.Configure = func(target string, opts configure.ConfigureOptions) error {
 		capturedOpts = opts
 		return nil
 	}
@@ -289,12 +238,9 @@ func TestConfigureCommand_EditFlag(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	oldCfgEdit := cfgEdit
-	t.Cleanup(func() {
-		cfgEdit = oldCfgEdit
-	})
-
-	cfgEdit = true
+	if err := configureCmd.Flags().Set("edit", "true"); err != nil {
+		t.Fatal(err)
+	}
 
 	// Execute
 	cmd := &cobra.Command{}
@@ -310,13 +256,13 @@ func TestConfigureCommand_EditFlag(t *testing.T) {
 
 // T032: Test --no-wait flag is passed correctly
 func TestConfigureCommand_NoWaitFlag(t *testing.T) {
-	// Mock ConfigureFunc
-	oldFunc := configure.ConfigureFunc
-	defer func() { configure.ConfigureFunc = oldFunc }()
+	// Mock app.Configure
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
 	var capturedOpts configure.ConfigureOptions
 
-	configure.ConfigureFunc = func(target string, opts configure.ConfigureOptions) error {
+	app.Configure = func(target string, opts configure.ConfigureOptions) error {
 		capturedOpts = opts
 		return nil
 	}
@@ -325,12 +271,9 @@ func TestConfigureCommand_NoWaitFlag(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	oldCfgNoWait := cfgNoWait
-	t.Cleanup(func() {
-		cfgNoWait = oldCfgNoWait
-	})
-
-	cfgNoWait = true
+	if err := configureCmd.Flags().Set("no-wait", "true"); err != nil {
+		t.Fatal(err)
+	}
 
 	// Execute
 	cmd := &cobra.Command{}
@@ -351,13 +294,13 @@ func TestConfigureCommand_NoWaitFlag(t *testing.T) {
 
 // T033: Test --profile flag changes target path
 func TestConfigureCommand_ProfileFlag(t *testing.T) {
-	// Mock ConfigureFunc
-	oldFunc := configure.ConfigureFunc
-	defer func() { configure.ConfigureFunc = oldFunc }()
+	// Mock app.Configure
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
 	var capturedTarget string
 
-	configure.ConfigureFunc = func(target string, opts configure.ConfigureOptions) error {
+	app.Configure = func(target string, opts configure.ConfigureOptions) error {
 		capturedTarget = target
 		return nil
 	}
@@ -366,12 +309,7 @@ func TestConfigureCommand_ProfileFlag(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	oldProfile := profile
-	t.Cleanup(func() {
-		profile = oldProfile
-	})
-
-	profile = "production"
+	app.profile = "production"
 
 	// Execute
 	cmd := &cobra.Command{}
@@ -395,20 +333,13 @@ func TestConfigureCommand_GeneratesNewStructure(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	// Backup and restore globals
-	oldCfgForce := cfgForce
-	oldCfgEdit := cfgEdit
-	oldProfile := profile
-	t.Cleanup(func() {
-		cfgForce = oldCfgForce
-		cfgEdit = oldCfgEdit
-		profile = oldProfile
-	})
+	app := NewApp()
+	configureCmd := newConfigureCommand(app)
 
 	// Set flags for force creation without editor
-	cfgForce = true
-	cfgEdit = false
-	profile = ""
+	if err := configureCmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
 
 	// Execute configure command
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
@@ -463,3 +394,317 @@ func TestConfigureCommand_GeneratesNewStructure(t *testing.T) {
 		t.Errorf("missing bar in foo section:\n%s", content)
 	}
 }
+
+func TestConfigureFormatReplacesOtherFormat(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	configureCmd := newConfigureCommand(NewApp())
+	writeProfileFile(t, DefaultProfile, "client-id: old\n")
+	yamlPath := filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))
+	envPath := filepath.Join(GetConfigPath(), DefaultProfile+".env")
+
+	// without --force the existing YAML file is kept
+	if err := configureCmd.Flags().Set("format", "dotenv"); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("configure RunE failed: %v", err)
+	}
+	if _, err := os.Stat(envPath); !os.IsNotExist(err) {
+		t.Fatalf("expected no dotenv file without --force, got: %v", err)
+	}
+
+	if err := configureCmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
+		t.Fatalf("configure RunE failed: %v", err)
+	}
+	if _, err := os.Stat(yamlPath); !os.IsNotExist(err) {
+		t.Errorf("expected YAML file to be replaced, got: %v", err)
+	}
+	b, err := os.ReadFile(envPath)
+	if err != nil || !contains(string(b), "COMMON_VAR2=123\n") {
+		t.Errorf("dotenv content = %q, %v", b, err)
+	}
+
+	if err := configureCmd.Flags().Set("format", "ini"); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestConfigureInteractive(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfileFile(t, DefaultProfile, "client-id: old\nclient-secret: s3cr3t\n")
+	target := filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))
+
+	run := func(input string) (string, string, error) {
+		configureCmd := newConfigureCommand(NewApp())
+		if err := configureCmd.Flags().Set("interactive", "true"); err != nil {
+			t.Fatal(err)
+		}
+		var out, errOut bytes.Buffer
+		wrapper := &cobra.Command{}
+		wrapper.SetIn(bytes.NewBufferString(input))
+		wrapper.SetOut(&out)
+		wrapper.SetErr(&errOut)
+		err := configureCmd.RunE(wrapper, nil)
+		return out.String(), errOut.String(), err
+	}
+
+	// the input ends before every field is answered: nothing is written
+	if _, _, err := run("new\n"); err == nil {
+		t.Fatal("expected an error for incomplete input")
+	}
+	if b, _ := os.ReadFile(target); string(b) != "client-id: old\nclient-secret: s3cr3t\n" {
+		t.Fatalf("expected the profile to be unchanged, got %q", b)
+	}
+
+	// client-id and client-secret keep their values; var2 is asked again
+	// until it is a valid int
+	out, errOut, err := run("\n\nx\n-1\nabc\n7\n\n\n")
+	if err != nil {
+		t.Fatalf("configure --interactive failed: %v", err)
+	}
+	if !contains(out, "client-id (client ID used to authenticate) [old]: ") ||
+		!contains(out, "client-secret (client secret used to authenticate) [********]: ") ||
+		!contains(out, "hoge.fuga (hoge fuga setting) [hello]: ") || contains(out, "s3cr3t") {
+		t.Errorf("unexpected prompts: %q", out)
+	}
+	if !contains(errOut, "common.var2 must be at least 0") || !contains(errOut, `invalid value "abc" for common.var2`) {
+		t.Errorf("expected invalid answers to be reported, got %q", errOut)
+	}
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"client-id: old\n", "client-secret: s3cr3t\n", "var1: x\n", "var2: 7\n", "fuga: hello\n"} {
+		if !contains(string(b), want) {
+			t.Errorf("expected %q in the written profile:\n%s", want, b)
+		}
+	}
+}
+
+// changingReader writes the profile at path on its first read, like a
+// concurrent `config set` made while the questions are answered.
+type changingReader struct {
+	t    *testing.T
+	path string
+	in   *bytes.Buffer
+	done bool
+}
+
+func (r *changingReader) Read(p []byte) (int, error) {
+	if !r.done {
+		r.done = true
+		if err := os.WriteFile(r.path, []byte("client-id: concurrent\n"), 0o600); err != nil {
+			r.t.Fatal(err)
+		}
+	}
+	return r.in.Read(p)
+}
+
+func TestConfigureInteractive_AbortsOnConcurrentChange(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfileFile(t, DefaultProfile, "client-id: old\n")
+	target := filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))
+
+	configureCmd := newConfigureCommand(NewApp())
+	if err := configureCmd.Flags().Set("interactive", "true"); err != nil {
+		t.Fatal(err)
+	}
+	wrapper := &cobra.Command{}
+	wrapper.SetIn(&changingReader{t: t, path: target, in: bytes.NewBufferString("new\n\n\n\n\n\n\n")})
+	wrapper.SetOut(&bytes.Buffer{})
+	wrapper.SetErr(&bytes.Buffer{})
+	if err := configureCmd.RunE(wrapper, nil); err == nil || !contains(err.Error(), "was changed while the questions were answered") {
+		t.Errorf("expected the concurrent change to abort, got %v", err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "client-id: concurrent\n" {
+		t.Errorf("expected the concurrent write to be kept, got %q", b)
+	}
+}
+
+func TestConfigureOverlays(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	partial := filepath.Join(home, "partial.json")
+	if err := os.WriteFile(partial, []byte(`{"client-id": "file", "common": {"var1": "file", "var2": 1}, "schema-version": 1}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MYCLI_COMMON_VAR1", "env")
+	t.Setenv("MYCLI_HOGE_FUGA", "env")
+
+	configureCmd := newConfigureCommand(NewApp())
+	for _, kv := range [][2]string{{"from-file", partial}, {"from-env", "true"}, {"set", "client-id=set"}, {"set", "common.var2=5"}} {
+		if err := configureCmd.Flags().Set(kv[0], kv[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := configureCmd.RunE(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("configure RunE failed: %v", err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"client-id: set\n", "var1: env\n", "var2: 5\n", "fuga: env\n", "bar: hello\n", "schema-version: " + strconv.Itoa(SchemaVersion()) + "\n"} {
+		if !contains(string(b), want) {
+			t.Errorf("expected %q in the written profile:\n%s", want, b)
+		}
+	}
+}
+
+func TestConfigureOverlays_Invalid(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	partial := filepath.Join(home, "partial.yaml")
+	if err := os.WriteFile(partial, []byte("common:\n  var2: 1.5\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string][2]string{
+		"not key=value": {"set", "client-id"},
+		"unknown key":   {"set", "common.var3=1"},
+		"not an int":    {"set", "common.var2=abc"},
+		"rule":          {"set", "common.var2=-1"},
+		"file value":    {"from-file", partial},
+		"missing file":  {"from-file", filepath.Join(home, "missing.yaml")},
+	}
+	for name, flag := range tests {
+		t.Run(name, func(t *testing.T) {
+			configureCmd := newConfigureCommand(NewApp())
+			if err := configureCmd.Flags().Set(flag[0], flag[1]); err != nil {
+				t.Fatal(err)
+			}
+			if err := configureCmd.RunE(&cobra.Command{}, nil); err == nil {
+				t.Error("expected an error")
+			}
+			if _, ok := FindConfigFile(DefaultProfile); ok {
+				t.Error("expected nothing to be written")
+			}
+		})
+	}
+}
+
+// TestConfigure_ErrorsWithoutUsage verifies that a rejected value is reported
+// without the usage text.
+func TestConfigure_ErrorsWithoutUsage(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+	app, out := newTestApp(t, t.TempDir())
+	root := NewRootCommand(app)
+	root.SetArgs([]string{"configure", "--set", "common.var2=abc"})
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected an error")
+	}
+	if errOut := app.Streams.Err.(*bytes.Buffer).String(); contains(out.String()+errOut, "Usage:") {
+		t.Errorf("expected no usage, got:\n%s%s", out.String(), errOut)
+	}
+}
+
+// TestConfigure_OverlaysRequireForce verifies that values given for an
+// existing profile fail without --force instead of being silently skipped.
+func TestConfigure_OverlaysRequireForce(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+	dir := t.TempDir()
+	path := filepath.Join(dir, "default.yaml")
+	if err := os.WriteFile(path, []byte("client-id: old\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	run := func(args ...string) error {
+		app, _ := newTestApp(t, dir)
+		root := NewRootCommand(app)
+		root.SetArgs(append([]string{"configure", "--set", "client-id=new"}, args...))
+		return root.Execute()
+	}
+	if err := run(); err == nil || !contains(err.Error(), "--force") || ExitCode(err) == ExitOK {
+		t.Errorf("expected an error asking for --force, got %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "client-id: old\n" {
+		t.Errorf("expected the profile to be unchanged, got %q", b)
+	}
+	if err := run("--force"); err != nil {
+		t.Fatalf("configure --force failed: %v", err)
+	}
+	if b, _ := os.ReadFile(path); !contains(string(b), "client-id: new\n") {
+		t.Errorf("expected the value to be applied, got %q", b)
+	}
+}
+
+func TestConfigureDryRunAndDiff(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfileFile(t, DefaultProfile, "client-id: old\nclient-secret: old-secret\n")
+	target := filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))
+
+	run := func(flags ...string) string {
+		configureCmd := newConfigureCommand(NewApp())
+		for _, f := range flags {
+			if err := configureCmd.Flags().Set(f, "true"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, kv := range []string{"client-id=new", "client-secret=new-secret"} {
+			if err := configureCmd.Flags().Set("set", kv); err != nil {
+				t.Fatal(err)
+			}
+		}
+		var out bytes.Buffer
+		wrapper := &cobra.Command{}
+		wrapper.SetOut(&out)
+		wrapper.SetErr(&bytes.Buffer{})
+		if err := configureCmd.RunE(wrapper, nil); err != nil {
+			t.Fatalf("configure RunE failed: %v", err)
+		}
+		return out.String()
+	}
+
+	if out := run("dry-run"); !contains(out, "client-id: new\n") || !contains(out, "hoge:\n") {
+		t.Errorf("expected the would-be file on stdout, got:\n%s", out)
+	}
+	out := run("diff")
+	if !contains(out, "--- "+target+"\n+++ "+target+"\n") || !contains(out, "-client-id: old\n") || !contains(out, "+client-id: new\n") {
+		t.Errorf("expected a diff against the existing profile, got:\n%s", out)
+	}
+	if contains(out, "old-secret") || contains(out, "new-secret") || !contains(out, "-client-secret: '********'\n") || !contains(out, "+client-secret: '******** (changed)'\n") {
+		t.Errorf("expected the secret to be masked on both sides, got:\n%s", out)
+	}
+	if out := run("diff", "show-secrets"); !contains(out, "-client-secret: old-secret\n") || !contains(out, "+client-secret: new-secret\n") {
+		t.Errorf("expected the secret with --show-secrets, got:\n%s", out)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "client-id: old\nclient-secret: old-secret\n" {
+		t.Errorf("expected the profile to be unchanged, got %q", b)
+	}
+}
+
+func TestConfigureForceKeepsExistingFormat(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(GetConfigPath(), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	jsonPath := filepath.Join(GetConfigPath(), DefaultProfile+".json")
+	if err := os.WriteFile(jsonPath, []byte(`{"client-id": "old"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	configureCmd := newConfigureCommand(NewApp())
+	if err := configureCmd.Flags().Set("force", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := configureCmd.RunE(&cobra.Command{}, nil); err != nil {
+		t.Fatalf("configure RunE failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))); !os.IsNotExist(err) {
+		t.Errorf("expected no YAML file without --format, got %v", err)
+	}
+	b, err := os.ReadFile(jsonPath)
+	if err != nil || !contains(string(b), `"client-id": ""`) || !contains(string(b), `"var2": 123`) {
+		t.Errorf("expected %s to be rewritten as JSON, got %q, %v", jsonPath, b, err)
+	}
+}
@@ -12,7 +12,7 @@ func TestConfigureWrapperCallsInternal(t *testing.T) {
 	dir := t.TempDir()
 	t.Setenv("HOME", dir)
 
-	// capture arguments passed to ConfigureFunc
+	// capture arguments passed to app.Configure
 	var calledTarget string
 	var calledForce bool
 	var calledEdit bool
@@ -21,8 +21,8 @@ func TestConfigureWrapperCallsInternal(t *testing.T) {
 	var calledShouldWait bool
 
 	// stub internal implementation
-	old := configure.ConfigureFunc
-	configure.ConfigureFunc = func(target string, opts configure.ConfigureOptions) error {
+	app := NewApp()
+	app.Configure = func(target string, opts configure.ConfigureOptions) error {
 		calledTarget = target
 		calledForce = opts.Force
 		calledEdit = opts.Edit
@@ -34,12 +34,13 @@ func TestConfigureWrapperCallsInternal(t *testing.T) {
 		}
 		return nil
 	}
-	defer func() { configure.ConfigureFunc = old }()
 
 	// ensure flags are set as expected
-	cfgForce = false
-	cfgEdit = true
-	profile = "dev"
+	configureCmd := newConfigureCommand(app)
+	if err := configureCmd.Flags().Set("edit", "true"); err != nil {
+		t.Fatal(err)
+	}
+	app.profile = "dev"
 	// Note: BuildEffectiveConfig now returns hardcoded defaults, not CliConfig values
 
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
@@ -47,7 +48,7 @@ func TestConfigureWrapperCallsInternal(t *testing.T) {
 	}
 
 	if calledTarget == "" {
-		t.Fatalf("internal ConfigureFunc was not called")
+		t.Fatalf("app.Configure was not called")
 	}
 	// target should be inside HOME/.config/mycli
 	if filepath.Base(calledTarget) != GetConfigFile("dev") {
@@ -89,21 +90,23 @@ func TestConfigureWrapper_NoWaitFlagPassed(t *testing.T) {
 	var calledShouldWait *bool
 
 	// stub internal implementation
-	old := configure.ConfigureFunc
-	configure.ConfigureFunc = func(target string, opts configure.ConfigureOptions) error {
+	app := NewApp()
+	app.Configure = func(target string, opts configure.ConfigureOptions) error {
 		if opts.EditorShouldWait != nil {
 			v := opts.EditorShouldWait("editor", []string{})
 			calledShouldWait = &v
 		}
 		return nil
 	}
-	defer func() { configure.ConfigureFunc = old }()
 
 	// set flags: enable edit and set no-wait
-	cfgForce = false
-	cfgEdit = true
-	cfgNoWait = true
-	profile = "dev"
+	configureCmd := newConfigureCommand(app)
+	for _, name := range []string{"edit", "no-wait"} {
+		if err := configureCmd.Flags().Set(name, "true"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app.profile = "dev"
 	// Note: BuildEffectiveConfig now returns hardcoded defaults, not CliConfig values
 
 	if err := configureCmd.RunE(&cobra.Command{}, []string{}); err != nil {
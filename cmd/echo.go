@@ -7,16 +7,17 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var echoCmd = &cobra.Command{
-	Use:   "echo [string...]",
-	Short: "Output text to standard output",
-	Long: `Echo writes the specified string(s) to standard output, separated by spaces,
+func newEchoCommand(app *App) *cobra.Command {
+	echoCmd := &cobra.Command{
+		Use:   "echo [string...]",
+		Short: "Output text to standard output",
+		Long: `Echo writes the specified string(s) to standard output, separated by spaces,
 followed by a newline.
 
 This is a UNIX-compatible echo command implementation with support for
 escape sequences and newline suppression options.`,
-	SilenceUsage: false, // T058: Show usage on errors
-	Example: `  # Basic output
+		SilenceUsage: false, // T058: Show usage on errors
+		Example: `  # Basic output
   mycli echo "Hello, World!"
   
   # Multiple arguments
@@ -33,35 +34,34 @@ escape sequences and newline suppression options.`,
   
   # Special escape: \c suppresses output
   mycli echo -e "Stop here\cIgnored text"`,
-	RunE: func(cmd *cobra.Command, args []string) error {
-		// Get flag values
-		suppressNewline, _ := cmd.Flags().GetBool("no-newline")
-		interpretEscapes, _ := cmd.Flags().GetBool("escape")
-		verbose, _ := cmd.Flags().GetBool("verbose")
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Get flag values
+			suppressNewline, _ := cmd.Flags().GetBool("no-newline")
+			interpretEscapes, _ := cmd.Flags().GetBool("escape")
+			verbose, _ := cmd.Flags().GetBool("verbose")
 
-		// T063: Verbose logging
-		if verbose {
-			cmd.PrintErrf("[DEBUG] Args: %v\n", args)
-			cmd.PrintErrf("[DEBUG] SuppressNewline: %v\n", suppressNewline)
-			cmd.PrintErrf("[DEBUG] InterpretEscapes: %v\n", interpretEscapes)
-		}
+			// T063: Verbose logging
+			if verbose {
+				cmd.PrintErrf("[DEBUG] Args: %v\n", args)
+				cmd.PrintErrf("[DEBUG] SuppressNewline: %v\n", suppressNewline)
+				cmd.PrintErrf("[DEBUG] InterpretEscapes: %v\n", interpretEscapes)
+			}
 
-		// Create options with configured streams
-		opts := echo.EchoOptions{
-			SuppressNewline:  suppressNewline,
-			InterpretEscapes: interpretEscapes,
-			Verbose:          verbose,
-			Args:             args,
-			Output:           cmd.OutOrStdout(),
-			ErrOutput:        cmd.ErrOrStderr(),
-		}
+			// Create options with configured streams
+			opts := echo.EchoOptions{
+				SuppressNewline:  suppressNewline,
+				InterpretEscapes: interpretEscapes,
+				Verbose:          verbose,
+				Args:             args,
+				Output:           cmd.OutOrStdout(),
+				ErrOutput:        cmd.ErrOrStderr(),
+			}
 
-		// Use the refactored Echo function via EchoFunc for testability
-		return echo.EchoFunc(opts)
-	},
-}
+			// Use the app's Echo function for testability
+			return app.Echo(opts)
+		},
+	}
 
-func init() {
 	// T019: Add -n/--no-newline flag
 	echoCmd.Flags().BoolP("no-newline", "n", false, "do not output the trailing newline")
 
@@ -71,5 +71,5 @@ func init() {
 	// T062: Add --verbose flag
 	echoCmd.Flags().Bool("verbose", false, "enable debug logging to stderr")
 
-	rootCmd.AddCommand(echoCmd)
+	return echoCmd
 }
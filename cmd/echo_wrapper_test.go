@@ -9,22 +9,21 @@ import (
 )
 
 func TestEchoWrapperCallsInternal(t *testing.T) {
-	// capture arguments passed to EchoFunc
+	// capture arguments passed to app.Echo
 	var calledSuppressNewline bool
 	var calledInterpretEscapes bool
 	var calledVerbose bool
 	var calledArgs []string
 
 	// stub internal implementation
-	old := echo.EchoFunc
-	echo.EchoFunc = func(opts echo.EchoOptions) error {
+	app := NewApp()
+	app.Echo = func(opts echo.EchoOptions) error {
 		calledSuppressNewline = opts.SuppressNewline
 		calledInterpretEscapes = opts.InterpretEscapes
 		calledVerbose = opts.Verbose
 		calledArgs = opts.Args
 		return nil
 	}
-	defer func() { echo.EchoFunc = old }()
 
 	// Create command with flags
 	cmd := &cobra.Command{}
@@ -49,11 +48,11 @@ func TestEchoWrapperCallsInternal(t *testing.T) {
 
 	// Execute
 	args := []string{"Hello", "World"}
-	if err := echoCmd.RunE(cmd, args); err != nil {
+	if err := newEchoCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("echo RunE failed: %v", err)
 	}
 
-	// Verify internal EchoFunc was called with correct options
+	// Verify app.Echo was called with correct options
 	if !calledSuppressNewline {
 		t.Errorf("expected SuppressNewline true, got false")
 	}
@@ -74,14 +73,13 @@ func TestEchoWrapper_DefaultFlags(t *testing.T) {
 	var calledVerbose bool
 
 	// stub internal implementation
-	old := echo.EchoFunc
-	echo.EchoFunc = func(opts echo.EchoOptions) error {
+	app := NewApp()
+	app.Echo = func(opts echo.EchoOptions) error {
 		calledSuppressNewline = opts.SuppressNewline
 		calledInterpretEscapes = opts.InterpretEscapes
 		calledVerbose = opts.Verbose
 		return nil
 	}
-	defer func() { echo.EchoFunc = old }()
 
 	// Create command with flags at default values
 	cmd := &cobra.Command{}
@@ -94,7 +92,7 @@ func TestEchoWrapper_DefaultFlags(t *testing.T) {
 
 	// Execute with default flags
 	args := []string{"test"}
-	if err := echoCmd.RunE(cmd, args); err != nil {
+	if err := newEchoCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("echo RunE failed: %v", err)
 	}
 
@@ -114,12 +112,11 @@ func TestEchoWrapper_VerboseFlag(t *testing.T) {
 	var calledVerbose bool
 
 	// stub internal implementation
-	old := echo.EchoFunc
-	echo.EchoFunc = func(opts echo.EchoOptions) error {
+	app := NewApp()
+	app.Echo = func(opts echo.EchoOptions) error {
 		calledVerbose = opts.Verbose
 		return nil
 	}
-	defer func() { echo.EchoFunc = old }()
 
 	// Create command with verbose flag enabled
 	cmd := &cobra.Command{}
@@ -136,7 +133,7 @@ func TestEchoWrapper_VerboseFlag(t *testing.T) {
 
 	// Execute
 	args := []string{"verbose", "test"}
-	if err := echoCmd.RunE(cmd, args); err != nil {
+	if err := newEchoCommand(app).RunE(cmd, args); err != nil {
 		t.Fatalf("echo RunE failed: %v", err)
 	}
 
@@ -151,8 +148,8 @@ func TestEchoWrapper_OutputStreams(t *testing.T) {
 	var capturedErrOutput *bytes.Buffer
 
 	// stub internal implementation
-	old := echo.EchoFunc
-	echo.EchoFunc = func(opts echo.EchoOptions) error {
+	app := NewApp()
+	app.Echo = func(opts echo.EchoOptions) error {
 		// Capture the output streams
 		if buf, ok := opts.Output.(*bytes.Buffer); ok {
 			capturedOutput = buf
@@ -162,7 +159,6 @@ func TestEchoWrapper_OutputStreams(t *testing.T) {
 		}
 		return nil
 	}
-	defer func() { echo.EchoFunc = old }()
 
 	// Create command with custom output streams
 	cmd := &cobra.Command{}
@@ -176,7 +172,7 @@ func TestEchoWrapper_OutputStreams(t *testing.T) {
 	cmd.Flags().Bool("verbose", false, "enable debug logging to stderr")
 
 	// Execute
-	if err := echoCmd.RunE(cmd, []string{"test"}); err != nil {
+	if err := newEchoCommand(app).RunE(cmd, []string{"test"}); err != nil {
 		t.Fatalf("echo RunE failed: %v", err)
 	}
 
@@ -193,12 +189,11 @@ func TestEchoWrapper_EmptyArgs(t *testing.T) {
 	var calledArgs []string
 
 	// stub internal implementation
-	old := echo.EchoFunc
-	echo.EchoFunc = func(opts echo.EchoOptions) error {
+	app := NewApp()
+	app.Echo = func(opts echo.EchoOptions) error {
 		calledArgs = opts.Args
 		return nil
 	}
-	defer func() { echo.EchoFunc = old }()
 
 	// Create command
 	cmd := &cobra.Command{}
@@ -210,7 +205,7 @@ func TestEchoWrapper_EmptyArgs(t *testing.T) {
 	cmd.Flags().Bool("verbose", false, "enable debug logging to stderr")
 
 	// Execute with empty args
-	if err := echoCmd.RunE(cmd, []string{}); err != nil {
+	if err := newEchoCommand(app).RunE(cmd, []string{}); err != nil {
 		t.Fatalf("echo RunE failed: %v", err)
 	}
 
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+)
+
+// ConfigErrorKind classifies the errors that abort loading the configuration.
+type ConfigErrorKind int
+
+const (
+	ConfigNotFound         ConfigErrorKind = iota + 1 // an explicitly given config file does not exist
+	ConfigParseError                                  // a config file cannot be decoded
+	ConfigPermissionDenied                            // a config file cannot be read
+	ProfileNotFound                                   // the active profile or one it extends does not exist
+	ConfigInvalid                                     // the merged settings are rejected, e.g. in strict mode
+	ConfigInsecure                                    // a config file with secrets is readable by other users, with strict-permissions
+)
+
+// Exit codes of mycli. Every ConfigErrorKind has its own code, so scripts can
+// tell a broken configuration from a failed command.
+const (
+	ExitOK                     = 0
+	ExitError                  = 1
+	ExitConfigNotFound         = 2
+	ExitConfigParseError       = 3
+	ExitConfigPermissionDenied = 4
+	ExitProfileNotFound        = 5
+	ExitConfigInvalid          = 6
+	ExitConfigInsecure         = 7
+)
+
+// ConfigError is returned when the configuration cannot be loaded. Path is
+// the config file concerned, if any.
+type ConfigError struct {
+	Kind ConfigErrorKind
+	Path string
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Path == "" || strings.Contains(e.Err.Error(), e.Path) {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode returns the exit code of the config error kind.
+func (k ConfigErrorKind) ExitCode() int {
+	switch k {
+	case ConfigNotFound:
+		return ExitConfigNotFound
+	case ConfigParseError:
+		return ExitConfigParseError
+	case ConfigPermissionDenied:
+		return ExitConfigPermissionDenied
+	case ProfileNotFound:
+		return ExitProfileNotFound
+	case ConfigInvalid:
+		return ExitConfigInvalid
+	case ConfigInsecure:
+		return ExitConfigInsecure
+	}
+	return ExitError
+}
+
+// ExitCode returns the exit code mycli terminates with after err.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var cfgErr *ConfigError
+	if errors.As(err, &cfgErr) {
+		return cfgErr.Kind.ExitCode()
+	}
+	return ExitError
+}
+
+// configReadError classifies an error reading the config file at path.
+func configReadError(path string, err error) *ConfigError {
+	kind := ConfigParseError
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		kind = ConfigNotFound
+	case errors.Is(err, fs.ErrPermission):
+		kind = ConfigPermissionDenied
+	}
+	return &ConfigError{Kind: kind, Path: path, Err: err}
+}
@@ -0,0 +1,232 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	if got := ExitCode(nil); got != ExitOK {
+		t.Errorf("ExitCode(nil) = %d", got)
+	}
+	if got := ExitCode(errors.New("boom")); got != ExitError {
+		t.Errorf("ExitCode(plain error) = %d", got)
+	}
+	wrapped := errors.Join(errors.New("x"), &ConfigError{Kind: ProfileNotFound, Err: errors.New("missing")})
+	if got := ExitCode(wrapped); got != ExitProfileNotFound {
+		t.Errorf("ExitCode(wrapped ConfigError) = %d", got)
+	}
+}
+
+// TestInitConfig_Errors verifies that every class of config loading error
+// aborts with its own exit code.
+func TestInitConfig_Errors(t *testing.T) {
+	tests := map[string]struct {
+		setup func(t *testing.T, app *App)
+		want  int
+	}{
+		"explicit file not found": {
+			func(t *testing.T, app *App) { app.cfgFile = filepath.Join(t.TempDir(), "missing.yaml") },
+			ExitConfigNotFound,
+		},
+		"parse error": {
+			func(t *testing.T, app *App) { writeProfileFile(t, DefaultProfile, "hoge: [unclosed\n") },
+			ExitConfigParseError,
+		},
+		"profile not found": {
+			func(t *testing.T, app *App) { app.profile = "nope" },
+			ExitProfileNotFound,
+		},
+		"ancestor not found": {
+			func(t *testing.T, app *App) {
+				writeProfileFile(t, "prod", "extends: base\n")
+				app.profile = "prod"
+			},
+			ExitProfileNotFound,
+		},
+		"inheritance cycle": {
+			func(t *testing.T, app *App) {
+				writeProfileFile(t, "a", "extends: b\n")
+				writeProfileFile(t, "b", "extends: a\n")
+				app.profile = "a"
+			},
+			ExitConfigInvalid,
+		},
+		"profile name escaping the config directory": {
+			func(t *testing.T, app *App) {
+				writeProfileFile(t, "prod", "extends: ../../base\n")
+				app.profile = "prod"
+			},
+			ExitConfigInvalid,
+		},
+		"value of the wrong type": {
+			func(t *testing.T, app *App) { writeProfileFile(t, DefaultProfile, "common:\n  var2: abc\n") },
+			ExitConfigInvalid,
+		},
+		"undefined placeholder": {
+			func(t *testing.T, app *App) { writeProfileFile(t, DefaultProfile, "client-id: ${nope}\n") },
+			ExitConfigInvalid,
+		},
+		"unresolvable reference": {
+			func(t *testing.T, app *App) {
+				writeProfileFile(t, DefaultProfile, "client-id: env:MYCLI_TEST_UNSET\n")
+				app.resolveRefs = true
+			},
+			ExitConfigInvalid,
+		},
+		"secret without passphrase": {
+			func(t *testing.T, app *App) {
+				t.Setenv(PassphraseEnv, "")
+				writeProfileFile(t, DefaultProfile, "client-secret: enc:v1:1:AA==:AA==\n")
+				app.resolveRefs = true
+			},
+			ExitConfigInvalid,
+		},
+		"unknown key in strict mode": {
+			func(t *testing.T, app *App) { writeProfileFile(t, DefaultProfile, "strict-config: true\nnope: 1\n") },
+			ExitConfigInvalid,
+		},
+		"readable secrets in strict mode": {
+			func(t *testing.T, app *App) {
+				writeProfileFile(t, DefaultProfile, "strict-permissions: true\nclient-secret: x\n")
+				if err := os.Chmod(filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile)), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExitConfigInsecure,
+		},
+	}
+	if os.Geteuid() != 0 {
+		tests["permission denied"] = struct {
+			setup func(t *testing.T, app *App)
+			want  int
+		}{
+			func(t *testing.T, app *App) {
+				writeProfileFile(t, DefaultProfile, "client-id: x\n")
+				if err := os.Chmod(filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile)), 0); err != nil {
+					t.Fatal(err)
+				}
+			},
+			ExitConfigPermissionDenied,
+		}
+	}
+
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			app := setupInitConfig(t)
+			tt.setup(t, app)
+			err := app.initConfig(false)
+			var cfgErr *ConfigError
+			if !errors.As(err, &cfgErr) {
+				t.Fatalf("expected a *ConfigError, got %v", err)
+			}
+			if got := ExitCode(err); got != tt.want {
+				t.Errorf("ExitCode = %d; want %d (%v)", got, tt.want, err)
+			}
+		})
+	}
+}
+
+func TestInitConfig_AllowMissingProfile(t *testing.T) {
+	app := setupInitConfig(t)
+	writeProfileFile(t, DefaultProfile, "client-id: d\n")
+	app.profile = "new"
+
+	if err := app.initConfig(true); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+	if app.Config.ClientID != "d" {
+		t.Errorf("ClientID = %q; want the default profile's value", app.Config.ClientID)
+	}
+	create, _, _ := app.root.Find([]string{"profile", "create"})
+	get, _, _ := app.root.Find([]string{"config", "get"})
+	if !allowsMissingProfile(create) || allowsMissingProfile(get) {
+		t.Error("expected profile subcommands, but not config get, to allow a missing profile")
+	}
+}
+
+// TestInitConfig_PermissionWarning verifies that readable files are only
+// reported when they hold secrets, and that fix-permissions repairs them.
+func TestInitConfig_PermissionWarning(t *testing.T) {
+	app := setupInitConfig(t)
+	var errOut bytes.Buffer
+	app.Streams.Err = &errOut
+	writeProfileFile(t, DefaultProfile, "client-id: x\n")
+	writeProfileFile(t, "dev", "client-secret: s3cr3t\n")
+	writeProfileFile(t, "ref", "client-secret: env:SECRET\n")
+	for _, name := range []string{DefaultProfile, "dev", "ref"} {
+		if err := os.Chmod(filepath.Join(GetConfigPath(), GetConfigFile(name)), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, name := range []string{DefaultProfile, "ref"} {
+		app.profile = name
+		if err := app.initConfig(false); err != nil {
+			t.Fatalf("initConfig(%s) failed: %v", name, err)
+		}
+	}
+	if strings.Contains(errOut.String(), "fix-permissions") {
+		t.Errorf("expected no permission warning without secrets, got %q", errOut.String())
+	}
+
+	app.profile = "dev"
+	if err := app.initConfig(false); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+	if !strings.Contains(errOut.String(), "dev.yaml is readable by group or others (mode 0644)") {
+		t.Errorf("expected a permission warning, got %q", errOut.String())
+	}
+
+	if _, err := runConfigCmd(t, newConfigFixPermissionsCommand(app)); err != nil {
+		t.Fatalf("config fix-permissions failed: %v", err)
+	}
+	for path, want := range map[string]os.FileMode{
+		GetConfigPath(): 0o700,
+		filepath.Join(GetConfigPath(), GetConfigFile("dev")):          0o600,
+		filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile)): 0o600,
+	} {
+		if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != want {
+			t.Errorf("mode of %s = %v, %v; want %04o", path, fi.Mode().Perm(), err, want)
+		}
+	}
+	errOut.Reset()
+	if err := app.initConfig(false); err != nil || strings.Contains(errOut.String(), "fix-permissions") {
+		t.Errorf("expected no permission warning after fix-permissions, got %q, %v", errOut.String(), err)
+	}
+}
+
+func TestInitConfig_NoConfig(t *testing.T) {
+	app := setupInitConfig(t)
+	writeProfileFile(t, DefaultProfile, "hoge: [unclosed\n")
+	t.Setenv("MYCLI_NO_CONFIG", "1")
+	t.Setenv("MYCLI_CLIENT_ID", "from-env")
+	app.profile = "nope"
+
+	if err := app.initConfig(false); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+	if len(app.layers) != 0 {
+		t.Errorf("expected no config layers, got %v", app.configLayerFiles())
+	}
+	if app.Config.ClientID != "from-env" {
+		t.Errorf("ClientID = %q; want environment variables to apply", app.Config.ClientID)
+	}
+}
+
+// setupInitConfig isolates initConfig from the host and returns a new App
+// whose command tree has bound the config flags.
+func setupInitConfig(t *testing.T) *App {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+	t.Setenv("MYCLI_PROFILE", "")
+	t.Setenv("MYCLI_CONFIG", "")
+	app := NewApp()
+	NewRootCommand(app)
+	return app
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"reflect"
+
+	"github.com/rising3/go-cli/internal/schema"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// layerFlag is the source name of values set through configuration flags.
+const layerFlag = "flag"
+
+// addConfigFlags registers a flag for every leaf field of Config, named after
+// its dotted key (e.g. --common.var1), and binds it to the same viper key so
+// that a flag given on the command line takes precedence over env variables
+// and config files. Help text is taken from the field's `desc` tag.
+func addConfigFlags(fs *pflag.FlagSet, vp *viper.Viper) error {
+	for _, f := range schema.Fields(Config{}) {
+		usage := f.Description()
+		if usage == "" {
+			usage = "override " + f.Key
+		}
+		if f.Secret() {
+			usage += " (secret)"
+		}
+
+		switch f.Type.Kind() {
+		case reflect.Bool:
+			fs.Bool(f.Key, false, usage)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			fs.Int(f.Key, 0, usage)
+		case reflect.Float32, reflect.Float64:
+			fs.Float64(f.Key, 0, usage)
+		case reflect.Slice:
+			fs.StringSlice(f.Key, nil, usage)
+		default:
+			fs.String(f.Key, "", usage)
+		}
+
+		if err := vp.BindPFlag(f.Key, fs.Lookup(f.Key)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// configFlagChanged reports whether the configuration flag for key was set
+// on the command line.
+func (a *App) configFlagChanged(key string) bool {
+	if a.root == nil {
+		return false
+	}
+	flag := a.root.PersistentFlags().Lookup(key)
+	return flag != nil && flag.Changed
+}
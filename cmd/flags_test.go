@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/schema"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// TestAddConfigFlags_RegistersEveryField verifies that a typed flag with
+// help text exists for every leaf of Config.
+func TestAddConfigFlags_RegistersEveryField(t *testing.T) {
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := addConfigFlags(fs, viper.New()); err != nil {
+		t.Fatalf("addConfigFlags failed: %v", err)
+	}
+
+	for _, f := range schema.Fields(Config{}) {
+		flag := fs.Lookup(f.Key)
+		if flag == nil {
+			t.Errorf("missing flag --%s", f.Key)
+			continue
+		}
+		if flag.Usage == "" {
+			t.Errorf("flag --%s has no help text", f.Key)
+		}
+	}
+	if got := fs.Lookup("common.var2").Value.Type(); got != "int" {
+		t.Errorf("--common.var2 type = %s; want int", got)
+	}
+	if !strings.Contains(fs.Lookup("client-secret").Usage, "(secret)") {
+		t.Error("expected secret marker in --client-secret help")
+	}
+}
+
+// TestAddConfigFlags_Precedence verifies that flags override env variables
+// and config files, and that unset flags do not shadow them.
+func TestAddConfigFlags_Precedence(t *testing.T) {
+	vp := viper.New()
+	vp.SetConfigType(CliConfigType)
+	if err := vp.ReadConfig(strings.NewReader("common:\n  var1: file\n  var2: 1\nhoge:\n  fuga: file\n")); err != nil {
+		t.Fatal(err)
+	}
+	vp.SetEnvPrefix(strings.ToUpper(CliName))
+	vp.SetEnvKeyReplacer(envKeyReplacer)
+	vp.AutomaticEnv()
+	t.Setenv("MYCLI_COMMON_VAR2", "2")
+	t.Setenv("MYCLI_COMMON_VAR1", "env")
+
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	if err := addConfigFlags(fs, vp); err != nil {
+		t.Fatalf("addConfigFlags failed: %v", err)
+	}
+	if err := fs.Parse([]string{"--common.var2=3"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var c Config
+	if err := vp.Unmarshal(&c); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if c.Common.Var2 != 3 {
+		t.Errorf("common.var2 = %d; want 3 from flag", c.Common.Var2)
+	}
+	if c.Common.Var1 != "env" {
+		t.Errorf("common.var1 = %q; want env", c.Common.Var1)
+	}
+	if c.Hoge.Fuga != "file" {
+		t.Errorf("hoge.fuga = %q; want file", c.Hoge.Fuga)
+	}
+}
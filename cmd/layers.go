@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+)
+
+// Names of the sources a configuration value can originate from.
+const (
+	layerSystem        = "system"
+	layerDefault       = "default"
+	layerProfilePrefix = "profile:"
+	layerProject       = "project"
+	layerExplicit      = "config"
+	layerEnv           = "env"
+	layerUnset         = "unset"
+)
+
+// configLayer is a config file merged by initConfig together with the
+// settings it contributed.
+type configLayer struct {
+	Name     string
+	Path     string
+	Settings map[string]interface{}
+}
+
+// envKeyReplacer maps dotted config keys to environment variable names.
+var envKeyReplacer = strings.NewReplacer(".", "_", "-", "_")
+
+// addConfigLayer records a config file read by initConfig.
+func (a *App) addConfigLayer(name, path string, settings map[string]interface{}) {
+	a.layers = append(a.layers, configLayer{Name: name, Path: path, Settings: settings})
+}
+
+// configEnvVar returns the environment variable viper.AutomaticEnv consults for key.
+func configEnvVar(key string) string {
+	return strings.ToUpper(CliName) + "_" + strings.ToUpper(envKeyReplacer.Replace(key))
+}
+
+// configOrigin returns the source layer and path (flag, file or variable
+// name) that the effective value of key comes from, following viper's
+// precedence.
+func (a *App) configOrigin(key string) (string, string) {
+	if a.configFlagChanged(key) {
+		return layerFlag, "--" + key
+	}
+	if name := configEnvVar(key); os.Getenv(name) != "" {
+		return layerEnv, name
+	}
+	for i := len(a.layers) - 1; i >= 0; i-- {
+		if _, ok := config.GetValue(a.layers[i].Settings, key); ok {
+			return a.layers[i].Name, a.layers[i].Path
+		}
+	}
+	return layerUnset, ""
+}
+
+// valueLocation returns where the effective value of key is set (see
+// configOrigin): its position in the config file it comes from, or the flag
+// or environment variable setting it. It is empty for unset keys.
+func (a *App) valueLocation(key string) config.Location {
+	source, path := a.configOrigin(key)
+	switch source {
+	case layerFlag, layerEnv, layerUnset:
+		return config.Location{File: path}
+	}
+	if loc, ok := config.Locate(path, key); ok {
+		return loc
+	}
+	return config.Location{File: path}
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/migrate"
+)
+
+// TestConfigOrigin verifies that env variables win over config layers and
+// later layers win over earlier ones.
+func TestConfigOrigin(t *testing.T) {
+	app := NewApp()
+	app.addConfigLayer(layerDefault, "/cfg/default.yaml", map[string]interface{}{
+		"client-id": "a",
+		"common":    map[string]interface{}{"var1": "x", "var2": 1},
+	})
+	app.addConfigLayer(layerProfilePrefix+"prod", "/cfg/prod.yaml", map[string]interface{}{
+		"common": map[string]interface{}{"var2": 2},
+	})
+	t.Setenv("MYCLI_CLIENT_ID", "from-env")
+
+	tests := []struct {
+		key, source, path string
+	}{
+		{"client-id", layerEnv, "MYCLI_CLIENT_ID"},
+		{"common.var1", layerDefault, "/cfg/default.yaml"},
+		{"common.var2", "profile:prod", "/cfg/prod.yaml"},
+		{"hoge.fuga", layerUnset, ""},
+	}
+	for _, tt := range tests {
+		source, path := app.configOrigin(tt.key)
+		if source != tt.source || path != tt.path {
+			t.Errorf("configOrigin(%q) = %q, %q; want %q, %q", tt.key, source, path, tt.source, tt.path)
+		}
+	}
+
+	if files := app.configLayerFiles(); len(files) != 2 || files[1] != "/cfg/prod.yaml" {
+		t.Errorf("configLayerFiles() = %v", files)
+	}
+}
+
+// configLayerFiles returns the paths of the app's layers in merge order.
+func (a *App) configLayerFiles() []string {
+	files := make([]string, len(a.layers))
+	for i, l := range a.layers {
+		files[i] = l.Path
+	}
+	return files
+}
+
+// TestWarnPendingMigrations verifies that only the files config migrate can
+// upgrade are warned about, and only when a migration would change them.
+func TestWarnPendingMigrations(t *testing.T) {
+	old := configMigrations
+	t.Cleanup(func() { configMigrations = old })
+
+	unversioned := map[string]interface{}{"client-id": "a"}
+	app := NewApp()
+	var errOut bytes.Buffer
+	app.Streams.Err = &errOut
+	app.addConfigLayer(layerSystem, "/etc/mycli/config.yaml", unversioned)
+	app.addConfigLayer(layerDefault, "/cfg/default.yaml", unversioned)
+	app.addConfigLayer(layerProject, "/work/.mycli.yaml", unversioned)
+
+	app.warnPendingMigrations()
+	if errOut.Len() != 0 {
+		t.Errorf("expected no warning when only the version would be recorded, got:\n%s", errOut.String())
+	}
+
+	configMigrations = append(append([]migrate.Migration{}, old...), migrate.Migration{
+		From:  len(old),
+		Apply: func(map[string]interface{}) error { return nil },
+	})
+	app.warnPendingMigrations()
+	if got := errOut.String(); strings.Count(got, "Warning:") != 1 || !strings.Contains(got, "/cfg/default.yaml") {
+		t.Errorf("expected a single warning for the user default file, got:\n%s", got)
+	}
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMain isolates the tests from config files on the host: the user config
+// directory follows the HOME set by each test, and no system-wide directory
+// is consulted.
+func TestMain(m *testing.M) {
+	_ = os.Unsetenv("XDG_CONFIG_HOME")
+	none := filepath.Join(os.TempDir(), "mycli-test-no-system-config")
+	_ = os.Setenv("XDG_CONFIG_DIRS", none)
+	systemConfigDir = none
+	os.Exit(m.Run())
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/cmd/migrate"
+	"github.com/spf13/cobra"
+)
+
+// configMigrations is the registry of config schema migrations. Step i
+// upgrades files from schema version i to i+1, so the current version is
+// the number of steps. When Config changes incompatibly, e.g. a key is
+// renamed, append a step such as:
+//
+//	{From: 1, Description: "rename hoge.fuga to hoge.piyo", Apply: func(data map[string]interface{}) error {
+//		migrate.RenameKey(data, "hoge.fuga", "hoge.piyo")
+//		return nil
+//	}},
+var configMigrations = []migrate.Migration{
+	{
+		From:        0,
+		Description: "record " + SchemaVersionKey,
+	},
+}
+
+// SchemaVersion returns the schema version written by configure.
+func SchemaVersion() int {
+	return migrate.Latest(configMigrations)
+}
+
+// warnPendingMigrations prints a warning for every config layer, written for
+// an older or newer schema version, that config migrate can upgrade. The
+// system and project files are left out, as are files whose pending
+// migrations would only record the version.
+func (a *App) warnPendingMigrations() {
+	opts := migrateOptions(nil)
+	for _, l := range a.layers {
+		if l.Name == layerSystem || l.Name == layerProject {
+			continue
+		}
+		pending, err := migrate.Pending(l.Settings, opts)
+		switch {
+		case err != nil:
+			fmt.Fprintf(a.Streams.Err, "Warning: %s: %v\n", l.Path, err)
+		case len(migrate.Changes(pending)) > 0:
+			fmt.Fprintf(a.Streams.Err, "Warning: %s needs %d pending migration(s) to %s %d; run \"%s config migrate\"\n",
+				l.Path, len(pending), SchemaVersionKey, SchemaVersion(), CliName)
+		}
+	}
+}
+
+// stampSchemaVersion records the current schema version in the settings of a
+// config file about to be written, unless they need a migration that changes
+// them (see migrate.Stamp). Empty settings belong to a new file, which is
+// written in the current schema.
+func stampSchemaVersion(data map[string]interface{}) {
+	if len(data) == 0 {
+		config.SetValue(data, SchemaVersionKey, SchemaVersion())
+		return
+	}
+	migrate.Stamp(data, migrateOptions(nil))
+}
+
+// migrateOptions builds migrate.Options for configMigrations. Streams are
+// bound to cmd when it is not nil.
+func migrateOptions(cmd *cobra.Command) migrate.Options {
+	opts := migrate.Options{
+		Key:        SchemaVersionKey,
+		Migrations: configMigrations,
+	}
+	if cmd != nil {
+		opts.Output = cmd.OutOrStdout()
+		opts.ErrOutput = cmd.ErrOrStderr()
+	}
+	return opts
+}
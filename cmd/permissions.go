@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/fileperm"
+	"github.com/rising3/go-cli/internal/secret"
+)
+
+// auditPermissions checks every config layer holding a secret value (see
+// hasSecrets) and warns when its file is readable by other users or owned by
+// another user. With strict-permissions it fails with a ConfigInsecure error
+// instead.
+func (a *App) auditPermissions() error {
+	for _, l := range a.layers {
+		if l.Path == "" || !hasSecrets(l.Settings) {
+			continue
+		}
+		issue, err := fileperm.Check(l.Path)
+		if err != nil || issue == nil {
+			continue
+		}
+		err = fmt.Errorf("%s; run \"%s config fix-permissions\"", issue, CliName)
+		if a.viper.GetBool(StrictPermissionsKey) {
+			return &ConfigError{Kind: ConfigInsecure, Path: l.Path, Err: err}
+		}
+		fmt.Fprintln(a.Streams.Err, "Warning:", err)
+	}
+	return nil
+}
+
+// hasSecrets reports whether settings hold a non-empty secret field that is
+// not a secret reference. Encrypted values count, as the envelope is only
+// as safe as the passphrase.
+func hasSecrets(settings map[string]interface{}) bool {
+	for _, key := range secretKeys() {
+		v, _ := config.GetValue(settings, key)
+		if s, ok := v.(string); ok && s != "" && !secret.IsReference(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionTargets returns the paths `config fix-permissions` repairs: the
+// config directory, every file directly inside it and the --config file.
+func (a *App) permissionTargets() ([]string, error) {
+	var paths []string
+	dir := a.configDir()
+	entries, err := os.ReadDir(dir)
+	switch {
+	case err == nil:
+		paths = append(paths, dir)
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				paths = append(paths, filepath.Join(dir, e.Name()))
+			}
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	if a.cfgFile != "" {
+		paths = append(paths, a.cfgFile)
+	}
+	return paths, nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	profilecmd "github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/spf13/cobra"
+)
+
+func newProfileCommand(app *App) *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:   "profile",
+		Short: "Manage configuration profiles",
+		Long: `Manage configuration profiles. A profile is a <name>.yaml file (or
+.yml, .json, .toml or .env) in the config directory that is merged over the
+default profile when selected. A profile may declare "extends: <name>" to be
+merged over another profile instead.
+
+The active profile is chosen, in order of precedence, by --profile,
+MYCLI_PROFILE, or the profile recorded with "profile use".`,
+		Annotations: map[string]string{allowMissingProfileAnnotation: "true"},
+	}
+
+	profileListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List profiles and show which one is active",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return profilecmd.List(app.activeProfile(), app.profileSource, app.profileOptions(cmd))
+		},
+	}
+
+	profileCreateCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a profile from the default scaffold",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			content, err := configure.Marshal(BuildEffectiveConfig(), CliConfigType)
+			if err != nil {
+				return err
+			}
+			return profilecmd.Create(args[0], content, app.profileOptions(cmd))
+		},
+	}
+
+	profileCopyCmd := &cobra.Command{
+		Use:   "copy <src> <dst>",
+		Short: "Copy a profile",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return profilecmd.Copy(args[0], args[1], app.profileOptions(cmd))
+		},
+	}
+
+	profileRenameCmd := &cobra.Command{
+		Use:   "rename <old> <new>",
+		Short: "Rename a profile",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return profilecmd.Rename(args[0], args[1], DefaultProfile, app.profileOptions(cmd))
+		},
+	}
+
+	profileDeleteCmd := &cobra.Command{
+		Use:   "delete <name>",
+		Short: "Delete a profile",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return profilecmd.Delete(args[0], DefaultProfile, app.profileOptions(cmd))
+		},
+	}
+
+	profileUseCmd := &cobra.Command{
+		Use:   "use <name>",
+		Short: "Set the profile used when --profile and MYCLI_PROFILE are not given",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return profilecmd.Use(args[0], app.profileOptions(cmd))
+		},
+	}
+
+	profileCmd.AddCommand(profileListCmd)
+	profileCmd.AddCommand(profileCreateCmd)
+	profileCmd.AddCommand(profileCopyCmd)
+	profileCmd.AddCommand(profileRenameCmd)
+	profileCmd.AddCommand(profileDeleteCmd)
+	profileCmd.AddCommand(profileUseCmd)
+	return profileCmd
+}
+
+// profileOptions builds profilecmd.Options for the app's config directory.
+// Streams are bound to cmd when it is not nil.
+func (a *App) profileOptions(cmd *cobra.Command) profilecmd.Options {
+	opts := profilecmd.Options{
+		Dir:  a.configDir(),
+		Ext:  CliConfigType,
+		Exts: configure.Extensions(),
+	}
+	if cmd != nil {
+		opts.Output = cmd.OutOrStdout()
+		opts.ErrOutput = cmd.ErrOrStderr()
+	}
+	return opts
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/schema"
+)
+
+// reloadDebounce is how long WatchConfig waits after the last change event
+// before reloading, so that an editor saving a file in several steps
+// triggers a single reload.
+var reloadDebounce = 100 * time.Millisecond
+
+// CurrentConfig returns the latest loaded configuration. Unlike the Config field,
+// which is set once by initConfig, it reflects reloads made by WatchConfig
+// and is safe to call from any goroutine.
+func (a *App) CurrentConfig() Config {
+	if cfg := a.current.Load(); cfg != nil {
+		return *cfg
+	}
+	return a.Config
+}
+
+// SubscribeConfig registers fn to be called with the new configuration after
+// every successful reload. The returned function removes the subscription.
+func (a *App) SubscribeConfig(fn func(Config)) (unsubscribe func()) {
+	a.subscribersMu.Lock()
+	defer a.subscribersMu.Unlock()
+	id := a.nextSubscriber
+	a.nextSubscriber++
+	a.subscribers[id] = fn
+	return func() {
+		a.subscribersMu.Lock()
+		defer a.subscribersMu.Unlock()
+		delete(a.subscribers, id)
+	}
+}
+
+// notifySubscribers calls every subscriber with cfg.
+func (a *App) notifySubscribers(cfg Config) {
+	a.subscribersMu.Lock()
+	fns := make([]func(Config), 0, len(a.subscribers))
+	for _, fn := range a.subscribers {
+		fns = append(fns, fn)
+	}
+	a.subscribersMu.Unlock()
+	for _, fn := range fns {
+		fn(cfg)
+	}
+}
+
+// WatchConfig watches the config files of the current configuration until
+// ctx is done. When the default file, the active profile chain or an explicit
+// --config file changes, the configuration is merged again and validated; a
+// valid result replaces CurrentConfig and is passed to the subscribers, while
+// an invalid one is reported to onError and the previous one is kept.
+// initConfig must have run before.
+func (a *App) WatchConfig(ctx context.Context, onError func(error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = watcher.Close() }()
+
+	// Directories are watched instead of files, as editors often replace a
+	// file by renaming a new one over it.
+	for _, dir := range a.watchedDirs() {
+		if err := watcher.Add(dir); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	var timer *time.Timer
+	reload := make(chan struct{}, 1)
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !a.isWatchedFile(event.Name) {
+				continue
+			}
+			if timer != nil {
+				timer.Stop()
+			}
+			timer = time.AfterFunc(reloadDebounce, func() {
+				select {
+				case reload <- struct{}{}:
+				default:
+				}
+			})
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			onError(err)
+		case <-reload:
+			if _, err := a.reloadConfig(); err != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// reloadConfig merges the config files again and validates the result. On
+// success the new configuration is stored and the subscribers are notified;
+// otherwise the app's viper and layers are restored.
+func (a *App) reloadConfig() (Config, error) {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	previous := a.layers
+	err := a.readConfigFiles(false)
+	var cfg Config
+	if err == nil {
+		cfg, err = a.validateReload()
+	}
+	if err != nil {
+		a.restoreLayers(previous)
+		return Config{}, fmt.Errorf("rejected config reload: %w", err)
+	}
+	a.current.Store(&cfg)
+	a.notifySubscribers(cfg)
+	return cfg, nil
+}
+
+// validateReload decodes the freshly merged settings and checks them against
+// the strict-config setting and the Config rules.
+func (a *App) validateReload() (Config, error) {
+	cfg, unknown, err := a.decodeConfig()
+	if err != nil {
+		return Config{}, err
+	}
+	if len(unknown) > 0 && a.viper.GetBool(StrictConfigKey) {
+		return Config{}, unknownKeysError(unknown)
+	}
+	violations, err := schema.ValidateRedacted(cfg, a.isSensitive)
+	if err != nil {
+		return Config{}, err
+	}
+	if len(violations) > 0 {
+		msgs := make([]string, len(violations))
+		for i, v := range violations {
+			msgs[i] = v.Key + ": " + v.Message
+		}
+		return Config{}, fmt.Errorf("invalid configuration: %s", strings.Join(msgs, "; "))
+	}
+	return cfg, nil
+}
+
+// restoreLayers rebuilds the app's viper from previously read layers.
+func (a *App) restoreLayers(layers []configLayer) {
+	a.resetViper()
+	for _, l := range layers {
+		_ = a.viper.MergeConfigMap(l.Settings)
+	}
+	a.layers = layers
+}
+
+// watchedFiles returns the config files whose changes trigger a reload: the
+// explicit --config file, or the default file and active profile chain in
+// any supported format, so that creating one is noticed as well.
+func (a *App) watchedFiles() []string {
+	if a.cfgFile != "" {
+		return []string{a.cfgFile}
+	}
+	names := []string{DefaultProfile}
+	for _, l := range a.layers {
+		if name, ok := strings.CutPrefix(l.Name, layerProfilePrefix); ok {
+			names = append(names, name)
+		}
+	}
+	if a.profile != "" {
+		names = append(names, a.profile)
+	}
+
+	var files []string
+	for _, name := range names {
+		for _, ext := range configure.Extensions() {
+			files = append(files, filepath.Join(a.configDir(), name+"."+ext))
+		}
+	}
+	return files
+}
+
+// watchedDirs returns the directories of watchedFiles.
+func (a *App) watchedDirs() []string {
+	var dirs []string
+	seen := map[string]bool{}
+	for _, f := range a.watchedFiles() {
+		if dir := filepath.Dir(f); !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// isWatchedFile reports whether a change of path triggers a reload. The set
+// is computed on every event, as a reload may change the profile chain.
+func (a *App) isWatchedFile(path string) bool {
+	for _, f := range a.watchedFiles() {
+		if filepath.Clean(path) == filepath.Clean(f) {
+			return true
+		}
+	}
+	return false
+}
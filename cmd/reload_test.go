@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupReload writes a valid default and prod profile and returns an App
+// that has loaded them.
+func setupReload(t *testing.T) *App {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	writeProfileFile(t, DefaultProfile, "hoge:\n  fuga: x\ncommon:\n  var2: 1\n")
+	writeProfileFile(t, "prod", "client-id: p1\n")
+	app := NewApp()
+	app.profile = "prod"
+	if err := app.initConfig(false); err != nil {
+		t.Fatalf("initConfig failed: %v", err)
+	}
+	return app
+}
+
+func TestReloadConfig_SwapsAndNotifies(t *testing.T) {
+	app := setupReload(t)
+
+	var notified []string
+	unsubscribe := app.SubscribeConfig(func(cfg Config) { notified = append(notified, cfg.ClientID) })
+
+	writeProfileFile(t, "prod", "client-id: p2\n")
+	if _, err := app.reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig failed: %v", err)
+	}
+	if got := app.CurrentConfig().ClientID; got != "p2" {
+		t.Errorf("CurrentConfig().ClientID = %q; want p2", got)
+	}
+
+	unsubscribe()
+	writeProfileFile(t, "prod", "client-id: p3\n")
+	if _, err := app.reloadConfig(); err != nil {
+		t.Fatalf("reloadConfig failed: %v", err)
+	}
+	if len(notified) != 1 || notified[0] != "p2" {
+		t.Errorf("notified = %v; want [p2]", notified)
+	}
+}
+
+func TestReloadConfig_RejectsInvalidEdits(t *testing.T) {
+	app := setupReload(t)
+
+	tests := map[string]string{
+		"parse error": "client-id: [\n",
+		"violation":   "client-id: p2\ncommon:\n  var2: -1\n",
+		"bad extends": "extends: missing\n",
+	}
+	for name, content := range tests {
+		writeProfileFile(t, "prod", content)
+		if _, err := app.reloadConfig(); err == nil {
+			t.Errorf("%s: expected reload to be rejected", name)
+		}
+		if got := app.CurrentConfig().ClientID; got != "p1" {
+			t.Errorf("%s: CurrentConfig().ClientID = %q; want p1", name, got)
+		}
+		if got := app.configLayerFiles(); len(got) != 2 {
+			t.Errorf("%s: layers not restored: %v", name, got)
+		}
+	}
+}
+
+func TestWatchConfig_ReloadsOnChange(t *testing.T) {
+	app := setupReload(t)
+	oldDebounce := reloadDebounce
+	reloadDebounce = 10 * time.Millisecond
+	t.Cleanup(func() { reloadDebounce = oldDebounce })
+
+	reloaded := make(chan Config, 1)
+	defer app.SubscribeConfig(func(cfg Config) { reloaded <- cfg })()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- app.WatchConfig(ctx, func(err error) { t.Error(err) }) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for {
+		// Rewrite until the watcher, which starts asynchronously, notices.
+		if err := os.WriteFile(filepath.Join(GetConfigPath(), "prod.yaml"), []byte("client-id: watched\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case cfg := <-reloaded:
+			if cfg.ClientID != "watched" {
+				t.Errorf("ClientID = %q; want watched", cfg.ClientID)
+			}
+			return
+		case <-time.After(200 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("config was not reloaded")
+		}
+	}
+}
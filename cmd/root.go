@@ -1,11 +1,22 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/rising3/go-cli/internal/cmd/config"
+	profilecmd "github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/interpolate"
+	"github.com/rising3/go-cli/internal/schema"
+	"github.com/rising3/go-cli/internal/secret"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -16,117 +27,606 @@ const (
 	CliConfigBase  = ".config"
 	CliConfigType  = "yaml"
 	DefaultProfile = "default"
+
+	// ExtendsKey names the profile whose settings a profile file inherits.
+	// Profiles without it extend DefaultProfile.
+	ExtendsKey = "extends"
+
+	// StrictConfigKey enables rejection of unknown configuration keys. It can
+	// be set with --strict-config, MYCLI_STRICT_CONFIG or in a config file.
+	StrictConfigKey = "strict-config"
+
+	// StrictPermissionsKey turns the warning about config files with secrets
+	// that other users can read into an error. It can be set with
+	// --strict-permissions, MYCLI_STRICT_PERMISSIONS or in a config file.
+	StrictPermissionsKey = "strict-permissions"
+
+	// SchemaVersionKey records the schema version a config file was written
+	// for. Files without it predate versioning (see configMigrations).
+	SchemaVersionKey = "schema-version"
+
+	// PassphraseEnv holds the passphrase that secret fields are encrypted
+	// with; NewPassphraseEnv holds the one `config rotate-key` switches to.
+	PassphraseEnv    = "MYCLI_PASSPHRASE"
+	NewPassphraseEnv = "MYCLI_NEW_PASSPHRASE"
 )
 
+// reservedKeys are configuration keys that are valid in config files but are
+// not part of Config.
+var reservedKeys = []string{ExtendsKey, StrictConfigKey, StrictPermissionsKey, SchemaVersionKey}
+
+// Config is the configuration of mycli. Besides `mapstructure`, leaf fields
+// declare their scaffold default, description, secret marker and validation
+// rules as struct tags (see package internal/schema); `mycli configure` and
+// `mycli config validate` are driven by them.
 type Config struct {
-	ClientID     string       `mapstructure:"client-id"`
-	ClientSecret string       `mapstructure:"client-secret"`
+	ClientID     string       `mapstructure:"client-id" desc:"client ID used to authenticate"`
+	ClientSecret string       `mapstructure:"client-secret" desc:"client secret used to authenticate" secret:"true"`
 	Common       CommonConfig `mapstructure:"common"`
 	Hoge         HogeConfig   `mapstructure:"hoge"`
 }
 
 type CommonConfig struct {
-	Var1 string `mapstructure:"var1"`
-	Var2 int    `mapstructure:"var2"`
+	Var1 string `mapstructure:"var1" desc:"common variable 1"`
+	Var2 int    `mapstructure:"var2" default:"123" desc:"common variable 2" validate:"min=0"`
 }
 
 type HogeConfig struct {
-	Fuga string    `mapstructure:"fuga"`
+	Fuga string    `mapstructure:"fuga" default:"hello" desc:"hoge fuga setting" validate:"required"`
 	Foo  FooConfig `mapstructure:"foo"`
 }
 
 type FooConfig struct {
-	Bar string `mapstructure:"bar"`
+	Bar string `mapstructure:"bar" default:"hello" desc:"hoge foo bar setting"`
 }
 
-var CliConfig Config
-
-var cfgFile string
-var profile string
+const (
+	profileSourceFlag   = "flag"
+	profileSourceEnv    = "env"
+	profileSourceStored = "stored"
+)
 
-var rootCmd = &cobra.Command{
-	Use:     CliName,
-	Version: CliVersion,
-	Short:   "A brief description of your application",
-	Long: `A longer description that spans multiple lines and likely contains
+// NewRootCommand builds the mycli command tree for app, which must have
+// been created with NewApp. The configuration is loaded into app before any
+// subcommand runs. An App serves a single command tree.
+func NewRootCommand(app *App) *cobra.Command {
+	rootCmd := &cobra.Command{
+		Use:     CliName,
+		Version: CliVersion,
+		Short:   "A brief description of your application",
+		Long: `A longer description that spans multiple lines and likely contains
 examples and usage of using your application. For example:
 
 Cobra is a CLI library for Go that empowers applications.
 This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
-	// Uncomment the following line if your bare application
-	// has an action associated with it:
-	// Run: func(cmd *cobra.Command, args []string) { },
+		// Uncomment the following line if your bare application
+		// has an action associated with it:
+		// Run: func(cmd *cobra.Command, args []string) { },
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if repairsConfig(cmd) {
+				if err := app.resolveEnvOverrides(); err != nil {
+					cmd.SilenceUsage = true
+					return err
+				}
+				return nil
+			}
+			if !needsConfig(cmd) {
+				return nil
+			}
+			app.resolveRefs = hasAnnotation(cmd, resolveReferencesAnnotation)
+			if err := app.initConfig(allowsMissingProfile(cmd)); err != nil {
+				cmd.SilenceUsage = true
+				return err
+			}
+			return nil
+		},
+	}
+	app.root = rootCmd
+	rootCmd.SetIn(app.Streams.In)
+	rootCmd.SetOut(app.Streams.Out)
+	rootCmd.SetErr(app.Streams.Err)
+
+	rootCmd.PersistentFlags().StringVar(&app.cfgFile, "config", "", "config file (default is "+filepath.Join(app.configDir(), GetConfigFile(DefaultProfile))+")")
+	rootCmd.PersistentFlags().StringVar(&app.profile, "profile", "", "config profile (e.g. dev, prod)")
+	rootCmd.PersistentFlags().BoolVar(&app.verbose, "verbose", false, "print debug information about config loading to stderr")
+	rootCmd.PersistentFlags().BoolVar(&app.noConfig, "no-config", false, "run without reading any config file")
+	rootCmd.PersistentFlags().Bool(StrictConfigKey, false, "reject unknown configuration keys")
+	cobra.CheckErr(app.viper.BindPFlag(StrictConfigKey, rootCmd.PersistentFlags().Lookup(StrictConfigKey)))
+	rootCmd.PersistentFlags().Bool(StrictPermissionsKey, false, "reject config files with secrets that other users can read")
+	cobra.CheckErr(app.viper.BindPFlag(StrictPermissionsKey, rootCmd.PersistentFlags().Lookup(StrictPermissionsKey)))
+	cobra.CheckErr(addConfigFlags(rootCmd.PersistentFlags(), app.viper))
+	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+
+	rootCmd.AddCommand(
+		newCatCommand(app),
+		newConfigCommand(app),
+		newConfigureCommand(app),
+		newEchoCommand(app),
+		newProfileCommand(app),
+	)
+	return rootCmd
 }
 
+// allowMissingProfileAnnotation marks commands that run even though the
+// active profile does not exist yet, such as those creating it.
+const allowMissingProfileAnnotation = "allow-missing-profile"
+
+// resolveReferencesAnnotation marks commands that read the values of secret
+// references and encrypted secrets. The other commands leave those fields
+// empty, so that loading the configuration never reads a file, runs a command
+// or needs a passphrase they do not use.
+const resolveReferencesAnnotation = "resolve-references"
+
+// Execute runs the mycli command tree of a new App and exits with the code
+// of its error (see ExitCode).
 func Execute() {
-	err := rootCmd.Execute()
+	err := NewRootCommand(NewApp()).Execute()
 	if err != nil {
-		os.Exit(1)
+		os.Exit(ExitCode(err))
 	}
 }
 
-func init() {
-	cobra.OnInitialize(initConfig)
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is "+filepath.Join(GetConfigPath(), GetConfigFile(DefaultProfile))+")")
-	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "config profile (e.g. dev, prod)")
-	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+// needsConfig reports whether cmd loads the configuration; help, shell
+// completion and the commands repairing config files (see repairsConfig)
+// work with a broken or rejected one.
+func needsConfig(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+			return false
+		}
+	}
+	return !repairsConfig(cmd)
+}
+
+// repairsConfig reports whether cmd manages profiles or repairs config
+// files: the profile commands, config set and unset, fix-permissions,
+// backups and restore. They only resolve the active profile (see
+// resolveEnvOverrides), so that a profile that cannot be loaded, such as
+// one whose extends chain is broken, can still be fixed with them.
+func repairsConfig(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		switch c.Name() {
+		case "profile", "set", "unset", "fix-permissions", "backups", "restore":
+			return true
+		}
+	}
+	return false
+}
+
+// allowsMissingProfile reports whether cmd or one of its parents carries the
+// allowMissingProfileAnnotation.
+func allowsMissingProfile(cmd *cobra.Command) bool {
+	return hasAnnotation(cmd, allowMissingProfileAnnotation)
+}
+
+// hasAnnotation reports whether cmd or one of its parents sets annotation to
+// "true".
+func hasAnnotation(cmd *cobra.Command, annotation string) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c.Annotations[annotation] == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// initConfig reads the config files and decodes the merged settings into
+// a.Config. Files that cannot be read, a missing profile (unless
+// allowMissingProfile), settings that cannot be decoded (see decodeConfig),
+// unknown keys in strict mode and, with strict-permissions, secrets that
+// other users can read abort with a *ConfigError.
+func (a *App) initConfig(allowMissingProfile bool) error {
+	if err := a.resolveEnvOverrides(); err != nil {
+		return err
+	}
+	if err := a.readConfigFiles(allowMissingProfile); err != nil {
+		return err
+	}
+
+	if a.verbose {
+		for _, l := range a.layers {
+			fmt.Fprintf(a.Streams.Err, "[DEBUG] Config file (%s): %s\n", l.Name, l.Path)
+		}
+	}
+	a.warnPendingMigrations()
+	if err := a.auditPermissions(); err != nil {
+		return err
+	}
+
+	cfg, unknown, err := a.decodeConfig()
+	if err != nil {
+		return &ConfigError{Kind: ConfigInvalid, Err: err}
+	}
+	a.Config = cfg
+	a.current.Store(&cfg)
+
+	if len(unknown) > 0 {
+		err := unknownKeysError(unknown)
+		if a.viper.GetBool(StrictConfigKey) {
+			return &ConfigError{Kind: ConfigInvalid, Err: err}
+		}
+		if a.verbose {
+			fmt.Fprintln(a.Streams.Err, "[DEBUG] Ignoring", err)
+		}
+	}
+	return nil
+}
+
+// readConfigFiles merges the config files into the app's viper, recording
+// them in a.layers: the explicit config file if one is given, otherwise the
+// default and profile files (see readDefaultAndMergeProfile).
+func (a *App) readConfigFiles(allowMissingProfile bool) error {
+	a.layers = nil
+	switch {
+	case a.noConfig:
+		a.resetViper()
+		return nil
+	case a.cfgFile != "":
+		return a.readExplicitConfig()
+	default:
+		return a.readDefaultAndMergeProfile(allowMissingProfile)
+	}
+}
+
+// decodeConfig expands the ${...} placeholders in the merged settings of the
+// app's viper, resolving the secret references and decrypting the secret
+// fields on the way (see resolveValue), unmarshals them into a Config and
+// returns it together with the unknown keys found in the settings. Values
+// that fail are cleared. The error joins every placeholder, reference,
+// decryption and type failure.
+func (a *App) decodeConfig() (Config, []string, error) {
+	raw := a.viper.AllSettings()
+	opts := a.interpolateOptions()
+	done := map[string]bool{}
+	opts.Resolve = func(key, value string) (string, error) {
+		v, err := a.resolveValue(raw, key, value)
+		done[key] = err == nil
+		return v, err
+	}
+	settings, expandErr := interpolate.Expand(raw, opts)
+	// Expand keeps the written value of a key that fails, such as a
+	// reference or an envelope; clear it instead
+	for _, key := range schema.Keys(Config{}) {
+		v, _ := config.GetValue(raw, key)
+		if _, ok := v.(string); ok && !done[key] {
+			config.SetValue(settings, key, "")
+		}
+	}
+	resolved := viper.New()
+	if err := resolved.MergeConfigMap(settings); err != nil {
+		return Config{}, nil, err
+	}
+
+	var cfg Config
+	var md mapstructure.Metadata
+	err := resolved.Unmarshal(&cfg, func(dc *mapstructure.DecoderConfig) { dc.Metadata = &md })
+	return cfg, unknownConfigKeys(md.Unused), errors.Join(expandErr, err)
+}
+
+// interpolateOptions returns the variables and environment ${...}
+// placeholders are expanded with.
+func (a *App) interpolateOptions() interpolate.Options {
+	return interpolate.Options{
+		Vars:      map[string]string{"profile": a.activeProfile()},
+		LookupEnv: os.LookupEnv,
+	}
+}
+
+// resolveValue returns the effective value of the Config field key, whose
+// value is expanded from raw: the value it points to when it is a secret
+// reference (env:, file: or exec:), and the plaintext when it is an
+// encrypted secret, using the passphrase in PassphraseEnv; either one must
+// be written as such in raw.
+// Since ${key} is replaced with the result, a placeholder sees the
+// effective value, which is not resolved a second time. References and
+// secrets are only resolved when a.resolveRefs is set and are cleared
+// otherwise, as are references rejected by checkReferenceOrigin. Errors
+// never contain resolved values.
+func (a *App) resolveValue(raw map[string]interface{}, key, value string) (string, error) {
+	f, ok := schema.Lookup(Config{}, key)
+	if !ok {
+		return value, nil
+	}
+	v, _ := config.GetValue(raw, key)
+	written := config.FormatValue(v)
+	switch {
+	case secret.IsReference(value) && secret.IsReference(written):
+		if !a.resolveRefs {
+			return "", nil
+		}
+		if err := a.checkReferenceOrigin(raw, key, value); err != nil {
+			return "", err
+		}
+		return a.resolver().Resolve(value)
+	case f.Secret() && secret.IsEncrypted(value) && secret.IsEncrypted(written):
+		if !a.resolveRefs {
+			return "", nil
+		}
+		plaintext, err := secret.Decrypt(value, os.Getenv(PassphraseEnv))
+		if errors.Is(err, secret.ErrNoPassphrase) {
+			err = fmt.Errorf("%s is not set", PassphraseEnv)
+		}
+		if err != nil {
+			return "", fmt.Errorf("decrypt: %w", err)
+		}
+		return plaintext, nil
+	}
+	return value, nil
+}
+
+// checkReferenceOrigin rejects ref, the expanded value of key, if it is a
+// file: or exec: reference and the value of key or of a key it interpolates
+// comes from a system config file or the project config file. Whoever wrote
+// those files, such as the author of a cloned repository, would otherwise
+// choose the files read and the commands run for the user.
+func (a *App) checkReferenceOrigin(raw map[string]interface{}, key, ref string) error {
+	prefix, _, _ := strings.Cut(ref, ":")
+	if prefix+":" != secret.FilePrefix && prefix+":" != secret.ExecPrefix {
+		return nil
+	}
+	for _, k := range append([]string{key}, interpolate.Sources(raw, key, a.interpolateOptions())...) {
+		if source, path := a.configOrigin(k); source == layerSystem || source == layerProject {
+			return fmt.Errorf("%s: references are not allowed in the %s config file %s", prefix, source, path)
+		}
+	}
+	return nil
+}
+
+// isReference reports whether the merged value of key is a secret reference.
+func (a *App) isReference(key string) bool {
+	return secret.IsReference(a.viper.GetString(key))
 }
 
-func initConfig() {
-	resolveEnvOverrides()
+// isSensitive reports whether the effective value of key is masked: it
+// belongs to a secret field, is resolved from a secret reference or
+// interpolates a value that is, such as "${client-secret}".
+func (a *App) isSensitive(key string) bool {
+	keys := append([]string{key}, interpolate.Sources(a.viper.AllSettings(), key, a.interpolateOptions())...)
+	for _, k := range keys {
+		if f, ok := schema.Lookup(Config{}, k); (ok && f.Secret()) || a.isReference(k) {
+			return true
+		}
+	}
+	return false
+}
 
-	if readExplicitConfig() {
-	} else {
-		readDefaultAndMergeProfile()
+// unknownConfigKeys returns the sorted keys left unused by decoding into
+// Config, excluding reservedKeys.
+func unknownConfigKeys(unused []string) []string {
+	var unknown []string
+	for _, key := range unused {
+		if !slices.Contains(reservedKeys, key) {
+			unknown = append(unknown, key)
+		}
 	}
+	sort.Strings(unknown)
+	return unknown
+}
 
-	viper.SetEnvPrefix(strings.ToUpper(CliName))
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
-	viper.AutomaticEnv()
+// unknownKeysError builds an error listing unknown keys together with a
+// "did you mean" suggestion taken from the known Config keys.
+func unknownKeysError(unknown []string) error {
+	candidates := append(schema.Keys(Config{}), schema.Sections(Config{})...)
+	candidates = append(candidates, reservedKeys...)
 
-	if err := viper.Unmarshal(&CliConfig); err != nil {
-		fmt.Fprintln(os.Stderr, "Failed to parse configuration:", err)
+	items := make([]string, len(unknown))
+	for i, key := range unknown {
+		items[i] = key
+		if s := schema.Suggest(key, candidates); s != "" {
+			items[i] += fmt.Sprintf(" (did you mean %q?)", s)
+		}
 	}
+	return fmt.Errorf("unknown configuration keys: %s", strings.Join(items, ", "))
 }
 
-func resolveEnvOverrides() {
-	if envCfg := os.Getenv("MYCLI_CONFIG"); envCfg != "" && cfgFile == "" {
-		cfgFile = envCfg
+// resolveEnvOverrides applies MYCLI_CONFIG and MYCLI_NO_CONFIG and selects
+// the active profile from --profile, MYCLI_PROFILE or the stored current
+// profile. A profile name that could escape the config directory is a
+// *ConfigError.
+func (a *App) resolveEnvOverrides() error {
+	if envCfg := os.Getenv("MYCLI_CONFIG"); envCfg != "" && a.cfgFile == "" {
+		a.cfgFile = envCfg
+	}
+	if v, err := strconv.ParseBool(os.Getenv("MYCLI_NO_CONFIG")); err == nil && v {
+		a.noConfig = true
+	}
+	switch {
+	case a.profile != "":
+		a.profileSource = profileSourceFlag
+	case os.Getenv("MYCLI_PROFILE") != "":
+		a.profile = os.Getenv("MYCLI_PROFILE")
+		a.profileSource = profileSourceEnv
+	default:
+		if stored, err := profilecmd.ReadCurrent(a.profileOptions(nil)); err == nil && stored != "" {
+			a.profile = stored
+			a.profileSource = profileSourceStored
+		}
 	}
-	if envProfile := os.Getenv("MYCLI_PROFILE"); envProfile != "" && profile == "" {
-		profile = envProfile
+	if a.profile != "" {
+		if err := profilecmd.ValidateName(a.profile); err != nil {
+			return &ConfigError{Kind: ConfigInvalid, Err: err}
+		}
 	}
+	return nil
 }
 
-func readExplicitConfig() bool {
-	if cfgFile == "" {
-		return false
+// readExplicitConfig reads the config file given with --config or
+// MYCLI_CONFIG into the app's viper.
+func (a *App) readExplicitConfig() error {
+	a.resetViper()
+	vp, err := readConfigFile(a.cfgFile)
+	if err != nil {
+		return configReadError(a.cfgFile, err)
 	}
+	if err := a.viper.MergeConfigMap(vp.AllSettings()); err != nil {
+		return &ConfigError{Kind: ConfigParseError, Path: a.cfgFile, Err: err}
+	}
+	a.addConfigLayer(layerExplicit, a.cfgFile, vp.AllSettings())
+	fmt.Fprintln(a.Streams.Err, "Using config file:", a.cfgFile)
+	return nil
+}
 
-	viper.SetConfigFile(cfgFile)
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
-	} else {
-		fmt.Fprintln(os.Stderr, "Failed to read config file:", err)
+// resetViper clears the settings read into the app's viper, so that every
+// layer is merged from scratch and repeated initialization does not
+// accumulate settings. Files are read with format-aware vipers (see
+// readConfigFile) and merged in, as the app's viper cannot decode dotenv
+// names into dotted keys.
+func (a *App) resetViper() {
+	a.viper.SetConfigType(CliConfigType)
+	_ = a.viper.ReadConfig(strings.NewReader(""))
+}
+
+// readDefaultAndMergeProfile merges, from lowest to highest precedence, the
+// system-wide default files, the user's default file, the active profile
+// chain and the project-local .mycli.yaml into the app's viper. None of the
+// files needs to exist, except the active profile unless allowMissingProfile.
+func (a *App) readDefaultAndMergeProfile(allowMissingProfile bool) error {
+	a.resetViper()
+
+	for _, dir := range a.systemConfigDirs() {
+		if path, ok := findConfigFile(dir, DefaultProfile); ok {
+			if err := a.mergeConfigFile(layerSystem, path); err != nil {
+				return err
+			}
+		}
+	}
+
+	if path, ok := a.findConfigFile(DefaultProfile); ok {
+		if err := a.mergeConfigFile(layerDefault, path); err != nil {
+			return err
+		}
 	}
-	return true
+
+	if a.profile != "" {
+		if err := a.mergeProfileChain(allowMissingProfile); err != nil {
+			return err
+		}
+	}
+
+	if wd, err := os.Getwd(); err == nil {
+		if path, ok := FindProjectConfig(wd); ok {
+			return a.mergeConfigFile(layerProject, path)
+		}
+	}
+	return nil
+}
+
+// mergeProfileChain merges the active profile and the profiles it extends.
+func (a *App) mergeProfileChain(allowMissingProfile bool) error {
+	chain, err := a.resolveProfileChain(a.profile)
+	if err != nil {
+		return err
+	}
+	if len(chain) == 0 && a.profile != DefaultProfile && !allowMissingProfile {
+		return &ConfigError{
+			Kind: ProfileNotFound,
+			Err:  fmt.Errorf("profile %q does not exist; create it with \"%s --profile %s configure\"", a.profile, CliName, a.profile),
+		}
+	}
+
+	order := []string{DefaultProfile}
+	for _, vp := range chain {
+		if err := a.viper.MergeConfigMap(vp.AllSettings()); err == nil {
+			fmt.Fprintln(a.Streams.Err, "Merged profile config:", vp.ConfigFileUsed())
+			a.addConfigLayer(layerProfilePrefix+profileName(vp), vp.ConfigFileUsed(), vp.AllSettings())
+			order = append(order, profileName(vp))
+		}
+	}
+	if a.verbose {
+		fmt.Fprintln(a.Streams.Err, "[DEBUG] Profile merge order:", strings.Join(order, " -> "))
+	}
+	return nil
 }
 
-func readDefaultAndMergeProfile() {
-	InitViper(viper.GetViper(), DefaultProfile)
+// mergeConfigFile merges the config file at path into the app's viper and
+// records it as a layer. Missing files are skipped silently.
+func (a *App) mergeConfigFile(layer, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	vp, err := readConfigFile(path)
+	if err != nil {
+		return configReadError(path, err)
+	}
+	if err := a.viper.MergeConfigMap(vp.AllSettings()); err != nil {
+		return &ConfigError{Kind: ConfigParseError, Path: path, Err: err}
+	}
+	a.addConfigLayer(layer, path, vp.AllSettings())
+	return nil
+}
 
-	_ = viper.ReadInConfig()
+// resolveProfileChain follows the ExtendsKey of the named profile and returns
+// the profile vipers ordered from the furthest ancestor to name itself.
+// The default profile ends every chain and is not included. A missing name
+// yields an empty chain, while an unreadable profile, a missing ancestor or
+// a cycle is a *ConfigError.
+func (a *App) resolveProfileChain(name string) ([]*viper.Viper, error) {
+	var chain []*viper.Viper
+	visited := map[string]bool{}
+	path := []string{}
 
-	if profile == "" {
-		return
+	for cur := name; cur != "" && cur != DefaultProfile; {
+		if err := profilecmd.ValidateName(cur); err != nil {
+			return nil, &ConfigError{Kind: ConfigInvalid, Err: err}
+		}
+		path = append(path, cur)
+		if visited[cur] {
+			return nil, &ConfigError{Kind: ConfigInvalid, Err: fmt.Errorf("profile inheritance cycle: %s", strings.Join(path, " -> "))}
+		}
+		visited[cur] = true
+
+		vp := a.newProfileViper(cur)
+		if err := vp.ReadInConfig(); err != nil {
+			var notFound viper.ConfigFileNotFoundError
+			switch {
+			case cur == name && errors.As(err, &notFound):
+				return nil, nil
+			case cur == name:
+				return nil, configReadError(vp.ConfigFileUsed(), fmt.Errorf("profile %q: %w", cur, err))
+			case errors.As(err, &notFound):
+				return nil, &ConfigError{Kind: ProfileNotFound, Err: fmt.Errorf("profile %q extended by %q: %w", cur, path[len(path)-2], err)}
+			}
+			return nil, configReadError(vp.ConfigFileUsed(), fmt.Errorf("profile %q extended by %q: %w", cur, path[len(path)-2], err))
+		}
+		chain = append([]*viper.Viper{vp}, chain...)
+		cur = vp.GetString(ExtendsKey)
 	}
+	return chain, nil
+}
 
-	vp := NewViper(profile)
+// checkExtends returns an error unless name may extend parent: parent must
+// be empty, the default profile, or a valid and existing profile whose own
+// chain reaches the default profile without a cycle and without passing
+// through name.
+func (a *App) checkExtends(name, parent string) error {
+	path := []string{name}
+	for cur := parent; cur != "" && cur != DefaultProfile; {
+		if err := profilecmd.ValidateName(cur); err != nil {
+			return err
+		}
+		seen := slices.Contains(path, cur)
+		path = append(path, cur)
+		if seen {
+			return fmt.Errorf("profile inheritance cycle: %s", strings.Join(path, " -> "))
+		}
 
-	if err := vp.ReadInConfig(); err == nil {
-		if err := viper.MergeConfigMap(vp.AllSettings()); err == nil {
-			fmt.Fprintln(os.Stderr, "Merged profile config:", vp.ConfigFileUsed())
+		vp := a.newProfileViper(cur)
+		if err := vp.ReadInConfig(); err != nil {
+			var notFound viper.ConfigFileNotFoundError
+			if errors.As(err, &notFound) {
+				return fmt.Errorf("profile %q extended by %q not found", cur, path[len(path)-2])
+			}
+			return configReadError(vp.ConfigFileUsed(), fmt.Errorf("profile %q: %w", cur, err))
 		}
+		cur = vp.GetString(ExtendsKey)
 	}
+	return nil
+}
+
+// profileName returns the profile name of a viper instance read from a profile file.
+func profileName(vp *viper.Viper) string {
+	base := filepath.Base(vp.ConfigFileUsed())
+	return strings.TrimSuffix(base, filepath.Ext(base))
 }
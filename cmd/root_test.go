@@ -3,7 +3,13 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/secret"
+	"github.com/spf13/viper"
 )
 
 // TestConfigUnmarshal_NewStructure verifies that Viper correctly unmarshals
@@ -193,3 +199,373 @@ common:
 		t.Errorf("Hoge.Foo.Bar = %q; want empty string", c.Hoge.Foo.Bar)
 	}
 }
+
+// TestResolveEnvOverrides_ProfileSource verifies that the active profile is
+// taken from the flag, then MYCLI_PROFILE, then the stored current profile.
+func TestResolveEnvOverrides_ProfileSource(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("MYCLI_PROFILE", "")
+
+	cfgDir := filepath.Join(dir, CliConfigBase, CliName)
+	if err := os.MkdirAll(cfgDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(cfgDir, "current-profile"), []byte("stored\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	if err := app.resolveEnvOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	if app.profile != "stored" || app.profileSource != profileSourceStored {
+		t.Errorf("got profile=%q source=%q; want stored/stored", app.profile, app.profileSource)
+	}
+
+	t.Setenv("MYCLI_PROFILE", "fromenv")
+	app = NewApp()
+	if err := app.resolveEnvOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	if app.profile != "fromenv" || app.profileSource != profileSourceEnv {
+		t.Errorf("got profile=%q source=%q; want fromenv/env", app.profile, app.profileSource)
+	}
+
+	app = NewApp()
+	app.profile = "fromflag"
+	if err := app.resolveEnvOverrides(); err != nil {
+		t.Fatal(err)
+	}
+	if app.profile != "fromflag" || app.profileSource != profileSourceFlag {
+		t.Errorf("got profile=%q source=%q; want fromflag/flag", app.profile, app.profileSource)
+	}
+
+	for _, name := range []string{"../victim", "a/b", ".hidden"} {
+		t.Setenv("MYCLI_PROFILE", name)
+		if err := NewApp().resolveEnvOverrides(); ExitCode(err) != ExitConfigInvalid {
+			t.Errorf("expected an invalid profile name error for %q, got %v", name, err)
+		}
+	}
+}
+
+// writeProfileFile writes content as the named profile in the config directory.
+func writeProfileFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(GetConfigPath(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(GetConfigPath(), GetConfigFile(name)), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestResolveProfileChain_Extends verifies that extends is followed
+// recursively and the chain is ordered from ancestor to leaf.
+func TestResolveProfileChain_Extends(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfileFile(t, DefaultProfile, "common:\n  var1: default\n")
+	writeProfileFile(t, "prod", "extends: default\ncommon:\n  var1: prod\n  var2: 1\n")
+	writeProfileFile(t, "staging", "extends: prod\ncommon:\n  var2: 2\n")
+
+	chain, err := NewApp().resolveProfileChain("staging")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var names []string
+	for _, vp := range chain {
+		names = append(names, profileName(vp))
+	}
+	if strings.Join(names, ",") != "prod,staging" {
+		t.Fatalf("chain = %v; want [prod staging]", names)
+	}
+
+	vp := NewViper(DefaultProfile)
+	if err := vp.ReadInConfig(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range chain {
+		if err := vp.MergeConfigMap(p.AllSettings()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := vp.GetString("common.var1"); got != "prod" {
+		t.Errorf("common.var1 = %q; want prod", got)
+	}
+	if got := vp.GetInt("common.var2"); got != 2 {
+		t.Errorf("common.var2 = %d; want 2", got)
+	}
+}
+
+// TestResolveProfileChain_Errors verifies cycle and missing-parent detection.
+func TestResolveProfileChain_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	writeProfileFile(t, "a", "extends: b\n")
+	writeProfileFile(t, "b", "extends: a\n")
+	writeProfileFile(t, "orphan", "extends: missing\n")
+
+	app := NewApp()
+	_, err := app.resolveProfileChain("a")
+	if err == nil || !strings.Contains(err.Error(), "cycle: a -> b -> a") {
+		t.Errorf("expected cycle error, got: %v", err)
+	}
+
+	_, err = app.resolveProfileChain("orphan")
+	if err == nil || !strings.Contains(err.Error(), `"missing" extended by "orphan"`) {
+		t.Errorf("expected missing parent error, got: %v", err)
+	}
+
+	chain, err := app.resolveProfileChain("nonexistent")
+	if err != nil || len(chain) != 0 {
+		t.Errorf("expected empty chain for missing profile, got %v, %v", chain, err)
+	}
+}
+
+// TestUnknownConfigKeys verifies that unused keys are reported with
+// suggestions while reserved profile keys are accepted.
+func TestUnknownConfigKeys(t *testing.T) {
+	vp := viper.New()
+	vp.SetConfigType(CliConfigType)
+	content := "extends: prod\nstrict-config: true\ncomon:\n  var1: x\ncommon:\n  var3: 1\n"
+	if err := vp.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	var c Config
+	var md mapstructure.Metadata
+	if err := vp.Unmarshal(&c, func(dc *mapstructure.DecoderConfig) { dc.Metadata = &md }); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	unknown := unknownConfigKeys(md.Unused)
+	if strings.Join(unknown, ",") != "common.var3,comon" {
+		t.Fatalf("unknown = %v; want [common.var3 comon]", unknown)
+	}
+
+	msg := unknownKeysError(unknown).Error()
+	if !strings.Contains(msg, `comon (did you mean "common"?)`) {
+		t.Errorf("expected suggestion for comon, got: %s", msg)
+	}
+	if !strings.Contains(msg, `common.var3 (did you mean "common.var1"?)`) {
+		t.Errorf("expected suggestion for common.var3, got: %s", msg)
+	}
+}
+
+// TestReadDefaultAndMergeProfile_Layers verifies the precedence of system,
+// user, profile and project-local config files.
+func TestReadDefaultAndMergeProfile_Layers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	sys := t.TempDir()
+	t.Setenv("XDG_CONFIG_DIRS", sys)
+	project := t.TempDir()
+	t.Chdir(project)
+
+	if err := os.MkdirAll(filepath.Join(sys, CliName), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(sys, CliName, GetConfigFile(DefaultProfile)), []byte("client-id: system\nclient-secret: system\nhoge:\n  fuga: system\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeProfileFile(t, DefaultProfile, "client-id: user\nclient-secret: user\n")
+	writeProfileFile(t, "prod", "client-id: prod\n")
+	if err := os.WriteFile(filepath.Join(project, GetProjectConfigFile()), []byte("client-id: project\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.profile = "prod"
+	if err := app.readDefaultAndMergeProfile(false); err != nil {
+		t.Fatalf("readDefaultAndMergeProfile failed: %v", err)
+	}
+
+	var names []string
+	for _, l := range app.layers {
+		names = append(names, l.Name)
+	}
+	if got := strings.Join(names, ","); got != "system,default,profile:prod,project" {
+		t.Errorf("layers = %s", got)
+	}
+	if got := app.viper.GetString("client-id"); got != "project" {
+		t.Errorf("client-id = %q; want project", got)
+	}
+	if got := app.viper.GetString("client-secret"); got != "user" {
+		t.Errorf("client-secret = %q; want user", got)
+	}
+	if got := app.viper.GetString("hoge.fuga"); got != "system" {
+		t.Errorf("hoge.fuga = %q; want system", got)
+	}
+}
+
+// TestReadDefaultAndMergeProfile_Formats verifies that profile files are
+// detected by extension and dotenv names are mapped back to dotted keys.
+func TestReadDefaultAndMergeProfile_Formats(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Chdir(t.TempDir())
+
+	if err := os.MkdirAll(GetConfigPath(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"default.toml": "client-id = 'toml'\n[common]\nvar1 = 'toml'\n",
+		"base.json":    `{"common": {"var2": 7}}`,
+		"prod.env":     "EXTENDS=base\nCLIENT_ID=env\nHOGE_FOO_BAR=\"a b\"\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(GetConfigPath(), name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := NewApp()
+	app.profile = "prod"
+	if err := app.readDefaultAndMergeProfile(false); err != nil {
+		t.Fatalf("readDefaultAndMergeProfile failed: %v", err)
+	}
+
+	var cfg Config
+	if err := app.viper.Unmarshal(&cfg); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if cfg.ClientID != "env" || cfg.Common.Var1 != "toml" || cfg.Common.Var2 != 7 || cfg.Hoge.Foo.Bar != "a b" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if got := app.configLayerFiles(); len(got) != 3 || filepath.Base(got[2]) != "prod.env" {
+		t.Errorf("layers = %v", got)
+	}
+}
+
+// TestDecodeConfig_ResolvesReferences verifies that secret references are
+// resolved before unmarshaling and that failures clear the value.
+func TestDecodeConfig_ResolvesReferences(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MYCLI_TEST_ID", "from-env")
+	if err := os.WriteFile(filepath.Join(home, "secret"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.resolveRefs = true
+	if err := app.viper.MergeConfigMap(map[string]interface{}{
+		"client-id":     "env:MYCLI_TEST_ID",
+		"client-secret": "file:~/secret",
+		"common":        map[string]interface{}{"var1": "exec:echo from-exec"},
+		"hoge":          map[string]interface{}{"fuga": "env:MYCLI_TEST_UNSET"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := app.decodeConfig()
+	if err == nil || !strings.Contains(err.Error(), "hoge.fuga: resolve env:MYCLI_TEST_UNSET") {
+		t.Errorf("expected resolve error for hoge.fuga, got: %v", err)
+	}
+	if cfg.ClientID != "from-env" || cfg.ClientSecret != "from-file" || cfg.Common.Var1 != "from-exec" || cfg.Hoge.Fuga != "" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+	if !app.isReference("common.var1") || app.isReference("common.var2") {
+		t.Error("isReference should report the raw merged value")
+	}
+}
+
+// TestDecodeConfig_Interpolates verifies that the placeholders of a
+// reference are expanded before it is resolved, while rawEntries keeps them as written.
+func TestDecodeConfig_Interpolates(t *testing.T) {
+	t.Setenv("MYCLI_TEST_HOST", "example.com")
+	app := NewApp()
+	app.profile = "prod"
+	app.resolveRefs = true
+	if err := app.viper.MergeConfigMap(map[string]interface{}{
+		"client-id": "env:MYCLI_TEST_${common.var1}",
+		"common":    map[string]interface{}{"var1": "HOST"},
+		"hoge": map[string]interface{}{
+			"fuga": "${env:MYCLI_TEST_HOST}/${profile}",
+			"foo":  map[string]interface{}{"bar": "$${profile}"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := app.decodeConfig()
+	if err != nil {
+		t.Fatalf("decodeConfig failed: %v", err)
+	}
+	if cfg.ClientID != "example.com" || cfg.Hoge.Fuga != "example.com/prod" || cfg.Hoge.Foo.Bar != "${profile}" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+
+	for _, e := range app.rawEntries() {
+		if e.Key == "hoge.fuga" && e.Value != "${env:MYCLI_TEST_HOST}/${profile}" {
+			t.Errorf("raw hoge.fuga = %v", e.Value)
+		}
+	}
+}
+
+// TestDecodeConfig_InterpolatesResolvedValues verifies that ${key} is
+// replaced with the effective value of a key holding a secret reference or
+// an encrypted secret, and that the value is not resolved a second time.
+func TestDecodeConfig_InterpolatesResolvedValues(t *testing.T) {
+	t.Setenv("MYCLI_TEST_VAR", "exec:touch pwned")
+	t.Setenv(PassphraseEnv, "pass")
+	oldIterations := secret.Iterations
+	secret.Iterations = 1000
+	t.Cleanup(func() { secret.Iterations = oldIterations })
+	envelope, err := secret.Encrypt("s3cret", "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := NewApp()
+	app.resolveRefs = true
+	if err := app.viper.MergeConfigMap(map[string]interface{}{
+		"client-id":     "${common.var1}",
+		"client-secret": envelope,
+		"common":        map[string]interface{}{"var1": "env:MYCLI_TEST_VAR"},
+		"hoge": map[string]interface{}{
+			"fuga": "x-${common.var1}",
+			"foo":  map[string]interface{}{"bar": "${client-secret}"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := app.decodeConfig()
+	if err != nil {
+		t.Fatalf("decodeConfig failed: %v", err)
+	}
+	if cfg.Hoge.Fuga != "x-exec:touch pwned" || cfg.ClientID != "exec:touch pwned" || cfg.Hoge.Foo.Bar != "s3cret" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
+
+// TestEffectiveEntries_MasksInterpolatedSecrets verifies that values copying
+// a secret field or a secret reference through ${...} are masked as well.
+func TestEffectiveEntries_MasksInterpolatedSecrets(t *testing.T) {
+	t.Setenv("MYCLI_TEST_TOKEN", "t0k3n")
+	app := NewApp()
+	app.resolveRefs = true
+	if err := app.viper.MergeConfigMap(map[string]interface{}{
+		"client-id":     "${hoge.fuga}",
+		"client-secret": "s1",
+		"common":        map[string]interface{}{"var1": "env:MYCLI_TEST_TOKEN"},
+		"hoge": map[string]interface{}{
+			"fuga": "${client-secret}",
+			"foo":  map[string]interface{}{"bar": "${common.var1}"},
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, _, err := app.decodeConfig()
+	if err != nil {
+		t.Fatalf("decodeConfig failed: %v", err)
+	}
+	for _, e := range app.effectiveEntries(cfg) {
+		switch e.Key {
+		case "client-id", "client-secret", "common.var1", "hoge.fuga", "hoge.foo.bar":
+			if e.Value != config.Mask {
+				t.Errorf("%s = %v; want it masked", e.Key, e.Value)
+			}
+		}
+	}
+}
@@ -5,28 +5,145 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/schema"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
 func NewViper(profile string) *viper.Viper {
-	vp := viper.New()
+	vp := newFileViper()
 	InitViper(vp, profile)
 	return vp
 }
 
+// InitViper points vp at the config file of profile. An existing file in any
+// supported format is used as is, with the format detected from its
+// extension; otherwise vp looks up <profile>.yaml in the config directory.
 func InitViper(vp *viper.Viper, profile string) {
+	initViper(vp, GetConfigPath(), profile)
+}
+
+// initViper points vp at the config file of profile in dir (see InitViper).
+func initViper(vp *viper.Viper, dir, profile string) {
+	if path, ok := findConfigFile(dir, profile); ok {
+		vp.SetConfigFile(path)
+		return
+	}
 	vp.SetConfigType(CliConfigType)
-	vp.AddConfigPath(GetConfigPath())
+	vp.AddConfigPath(dir)
 	vp.SetConfigName(profile)
 }
 
+// newProfileViper returns a viper instance for the config file of profile in
+// the app's config directory.
+func (a *App) newProfileViper(profile string) *viper.Viper {
+	vp := newFileViper()
+	initViper(vp, a.configDir(), profile)
+	return vp
+}
+
+// newFileViper returns a viper instance for reading a single config file.
+// Its dotenv codec maps variable names such as COMMON_VAR2 back to the
+// dotted Config keys.
+func newFileViper() *viper.Viper {
+	codec := configure.DotenvCodec{Keys: append(schema.Keys(Config{}), reservedKeys...)}
+	reg := viper.NewCodecRegistry()
+	cobra.CheckErr(reg.RegisterCodec("dotenv", codec))
+	cobra.CheckErr(reg.RegisterCodec("env", codec))
+	return viper.NewWithOptions(viper.WithCodecRegistry(reg))
+}
+
+// readConfigFile reads the config file at path, detecting its format from
+// the extension.
+func readConfigFile(path string) (*viper.Viper, error) {
+	vp := newFileViper()
+	vp.SetConfigFile(path)
+	if err := vp.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return vp, nil
+}
+
+// FindConfigFile returns the path of the config file of profile in the
+// config directory, trying every supported extension in order.
+func FindConfigFile(profile string) (string, bool) {
+	return findConfigFile(GetConfigPath(), profile)
+}
+
+// findConfigFile returns the path of the config file of profile in the app's
+// config directory (see FindConfigFile).
+func (a *App) findConfigFile(profile string) (string, bool) {
+	return findConfigFile(a.configDir(), profile)
+}
+
+// findConfigFile returns the first existing file dir/name.<ext> for the
+// supported extensions.
+func findConfigFile(dir, name string) (string, bool) {
+	for _, ext := range configure.Extensions() {
+		path := filepath.Join(dir, name+"."+ext)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// GetConfigPath returns the user config directory: $XDG_CONFIG_HOME/mycli
+// when XDG_CONFIG_HOME is set to an absolute path, otherwise $HOME/.config/mycli.
 func GetConfigPath() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+		return filepath.Join(xdg, CliName)
+	}
 	home, err := os.UserHomeDir()
 	cobra.CheckErr(err)
 	return filepath.Join(home, CliConfigBase, CliName)
 }
 
+// systemConfigDir is the system-wide config directory with the lowest precedence.
+var systemConfigDir = filepath.Join("/etc", CliName)
+
+// GetSystemConfigPaths returns the system-wide config directories in ascending
+// precedence: /etc/mycli first, then every $XDG_CONFIG_DIRS entry (default
+// /etc/xdg) with "mycli" appended, from last to first as the first entry is
+// the most important.
+func GetSystemConfigPaths() []string {
+	xdgDirs := os.Getenv("XDG_CONFIG_DIRS")
+	if xdgDirs == "" {
+		xdgDirs = "/etc/xdg"
+	}
+	dirs := filepath.SplitList(xdgDirs)
+
+	paths := []string{systemConfigDir}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if filepath.IsAbs(dirs[i]) {
+			paths = append(paths, filepath.Join(dirs[i], CliName))
+		}
+	}
+	return paths
+}
+
+// GetProjectConfigFile returns the file name of the project-local config.
+func GetProjectConfigFile() string {
+	return "." + CliName + "." + strings.ToLower(CliConfigType)
+}
+
+// FindProjectConfig walks up from dir to the filesystem root and returns the
+// path of the nearest project-local config file (.mycli.yaml, or .mycli with
+// another supported extension).
+func FindProjectConfig(dir string) (string, bool) {
+	for {
+		if path, ok := findConfigFile(dir, "."+CliName); ok {
+			return path, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", false
+		}
+		dir = parent
+	}
+}
+
 func GetConfigFile(profile string) string {
 	if profile == DefaultProfile {
 		return DefaultProfile + "." + strings.ToLower(CliConfigType)
@@ -38,20 +155,11 @@ func GetConfigFile(profile string) string {
 
 // BuildEffectiveConfig returns the effective configuration as a plain map.
 // It returns a nested map with default values for all configuration fields,
+// derived from the `default` tags of Config, and the current schema version,
 // suitable for generating new configuration files via the configure command.
 func BuildEffectiveConfig() map[string]interface{} {
-	return map[string]interface{}{
-		"client-id":     "",
-		"client-secret": "",
-		"common": map[string]interface{}{
-			"var1": "",
-			"var2": 123,
-		},
-		"hoge": map[string]interface{}{
-			"fuga": "hello",
-			"foo": map[string]interface{}{
-				"bar": "hello",
-			},
-		},
-	}
+	data, err := schema.Scaffold(Config{})
+	cobra.CheckErr(err)
+	data[SchemaVersionKey] = SchemaVersion()
+	return data
 }
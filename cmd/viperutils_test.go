@@ -3,8 +3,10 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/rising3/go-cli/internal/schema"
 	"github.com/spf13/viper"
 	"gopkg.in/yaml.v3"
 )
@@ -94,6 +96,9 @@ func TestBuildEffectiveConfig_HasAllFields(t *testing.T) {
 	if _, ok := cfg["hoge"]; !ok {
 		t.Error("missing key: hoge")
 	}
+	if got := cfg[SchemaVersionKey]; got != SchemaVersion() {
+		t.Errorf("%s = %v; want %d", SchemaVersionKey, got, SchemaVersion())
+	}
 
 	// Verify common nested keys
 	common, ok := cfg["common"].(map[string]interface{})
@@ -220,3 +225,68 @@ func TestBuildEffectiveConfig_YAMLMarshal(t *testing.T) {
 		t.Errorf("hoge.foo.bar after round-trip = %v; want hello", foo["bar"])
 	}
 }
+
+// TestBuildEffectiveConfig_CoversEveryConfigField verifies that the scaffold
+// is derived from Config, so every leaf field has an entry.
+func TestBuildEffectiveConfig_CoversEveryConfigField(t *testing.T) {
+	cfg := BuildEffectiveConfig()
+	for _, key := range schema.Keys(Config{}) {
+		var cur interface{} = cfg
+		for _, part := range strings.Split(key, ".") {
+			m, ok := cur.(map[string]interface{})
+			if !ok {
+				t.Fatalf("scaffold section for %s is not a map", key)
+			}
+			if cur, ok = m[part]; !ok {
+				t.Errorf("scaffold is missing key %s", key)
+				break
+			}
+		}
+	}
+}
+
+func TestGetConfigPath_XDGConfigHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	xdg := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	if got, want := GetConfigPath(), filepath.Join(xdg, CliName); got != want {
+		t.Errorf("GetConfigPath() = %q; want %q", got, want)
+	}
+
+	// relative values are ignored as required by the XDG spec
+	t.Setenv("XDG_CONFIG_HOME", "relative")
+	if got, want := GetConfigPath(), filepath.Join(home, CliConfigBase, CliName); got != want {
+		t.Errorf("GetConfigPath() = %q; want %q", got, want)
+	}
+}
+
+func TestGetSystemConfigPaths_Precedence(t *testing.T) {
+	t.Setenv("XDG_CONFIG_DIRS", "/first:/second:relative")
+	got := GetSystemConfigPaths()
+	want := []string{systemConfigDir, filepath.Join("/second", CliName), filepath.Join("/first", CliName)}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("GetSystemConfigPaths() = %v; want %v", got, want)
+	}
+}
+
+func TestFindProjectConfig_WalksUp(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := FindProjectConfig(nested); ok {
+		t.Fatal("expected no project config before it is created")
+	}
+
+	want := filepath.Join(root, GetProjectConfigFile())
+	if err := os.WriteFile(want, []byte("common:\n  var2: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := FindProjectConfig(nested)
+	if !ok || got != want {
+		t.Errorf("FindProjectConfig() = %q, %v; want %q", got, ok, want)
+	}
+}
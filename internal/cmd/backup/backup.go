@@ -0,0 +1,98 @@
+// Package backup implements the business logic of the `config backups` and
+// `config restore` commands, which list and roll back to the versions of a
+// profile file saved by package configfile.
+package backup
+
+import (
+	"fmt"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/configfile"
+)
+
+// Backups returns the backups of the profile name, newest first. Backups of
+// the profile in every recognized format are included, so that a profile
+// whose format was changed or that was deleted can be restored.
+func Backups(name string, opts profile.Options) ([]configfile.Backup, error) {
+	if err := profile.ValidateName(name); err != nil {
+		return nil, err
+	}
+	exts := opts.Exts
+	if len(exts) == 0 {
+		exts = []string{opts.Ext}
+	}
+	paths := make([]string, len(exts))
+	for i, ext := range exts {
+		paths[i] = filepath.Join(opts.Dir, name+"."+ext)
+	}
+	return configfile.Backups(paths...)
+}
+
+// List writes the backups of the profile name to opts.Output as a table of
+// versions, the local time they were taken and their files.
+func List(name string, opts profile.Options) error {
+	backups, err := Backups(name, opts)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("no backups of profile %s", name)
+	}
+	w := tabwriter.NewWriter(opts.Output, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "VERSION\tTIME\tFILE")
+	for _, b := range backups {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", b.Version, b.Time.Local().Format(time.DateTime), b.Path)
+	}
+	return w.Flush()
+}
+
+// Restore rolls the profile name back to the backup with the given version,
+// or to the newest one when version is empty. The version it replaces is
+// backed up in turn, and the profile is locked meanwhile (see
+// configure.Lock). When the backup has another format than the current
+// profile file, the current file is removed (and backed up) so that the
+// restored one takes effect.
+func Restore(name, version string, opts profile.Options) error {
+	backups, err := Backups(name, opts)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		return fmt.Errorf("no backups of profile %s", name)
+	}
+	b := backups[0]
+	if version != "" {
+		found := false
+		for _, candidate := range backups {
+			if candidate.Version == version {
+				b, found = candidate, true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("no backup %s of profile %s (see \"config backups %s\")", version, name, name)
+		}
+	}
+
+	err = configure.WithLock(b.Original, func() error {
+		current := profile.Path(name, opts)
+		if err := configfile.Restore(b); err != nil {
+			return err
+		}
+		if current != b.Original {
+			return configfile.Remove(current)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintf(opts.ErrOutput, "Restored %s from version %s\n", b.Original, b.Version)
+	}
+	return nil
+}
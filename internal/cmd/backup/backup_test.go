@@ -0,0 +1,83 @@
+package backup_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/backup"
+	"github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/configfile"
+)
+
+func newOptions(t *testing.T) (profile.Options, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	return profile.Options{
+		Dir:       t.TempDir(),
+		Ext:       "yaml",
+		Exts:      []string{"yaml", "json"},
+		Output:    &out,
+		ErrOutput: &bytes.Buffer{},
+	}, &out
+}
+
+func TestListAndRestore(t *testing.T) {
+	opts, out := newOptions(t)
+	path := filepath.Join(opts.Dir, "dev.yaml")
+	if err := configfile.WriteFile(path, []byte("client-id: v1\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := backup.List("dev", opts); err == nil {
+		t.Error("expected an error without backups")
+	}
+	if err := configfile.WriteFile(path, []byte("client-id: v2\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backup.List("dev", opts); err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "VERSION") || !strings.Contains(lines[1], "dev.yaml.") {
+		t.Errorf("unexpected listing:\n%s", out.String())
+	}
+
+	if err := backup.Restore("dev", "nope", opts); err == nil || !strings.Contains(err.Error(), "no backup nope") {
+		t.Errorf("expected an unknown version error, got %v", err)
+	}
+	if err := backup.Restore("dev", "", opts); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "client-id: v1\n" {
+		t.Errorf("restored content = %q; want v1", b)
+	}
+}
+
+// TestRestore_OtherFormat verifies that restoring a backup in another format
+// replaces the current profile file.
+func TestRestore_OtherFormat(t *testing.T) {
+	opts, _ := newOptions(t)
+	yamlPath, jsonPath := filepath.Join(opts.Dir, "dev.yaml"), filepath.Join(opts.Dir, "dev.json")
+	if err := os.WriteFile(jsonPath, []byte(`{"client-id": "json"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configfile.Remove(jsonPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := configfile.WriteFile(yamlPath, []byte("client-id: yaml\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backup.Restore("dev", "", opts); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if _, err := os.Stat(yamlPath); !os.IsNotExist(err) {
+		t.Error("expected dev.yaml to be removed")
+	}
+	if got := profile.Path("dev", opts); got != jsonPath {
+		t.Errorf("profile path = %s; want %s", got, jsonPath)
+	}
+}
@@ -0,0 +1,158 @@
+// Package bundle implements the business logic of the `config export` and
+// `config import` commands, which pack profile files into a portable tar.gz
+// archive described by a manifest and restore them.
+package bundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/profile"
+)
+
+// ManifestFile is the name of the manifest inside a bundle.
+const ManifestFile = "manifest.json"
+
+// FormatVersion is the version of the bundle layout written by Export.
+// Import rejects bundles with a newer version.
+const FormatVersion = 1
+
+// profilesDir is the directory holding the profile files inside a bundle.
+const profilesDir = "profiles/"
+
+// maxFileSize bounds the size of a single file read from a bundle.
+const maxFileSize = 1 << 20
+
+// Manifest describes the content of a bundle.
+type Manifest struct {
+	FormatVersion int       `json:"format-version"`
+	CLI           string    `json:"cli"`
+	CLIVersion    string    `json:"cli-version,omitempty"`
+	SchemaVersion int       `json:"schema-version"`
+	Created       time.Time `json:"created"`
+	Redacted      bool      `json:"redacted"`
+	Profiles      []Profile `json:"profiles"`
+}
+
+// Profile is a profile file stored in a bundle.
+type Profile struct {
+	Name      string   `json:"name"`
+	File      string   `json:"file"`
+	Redacted  []string `json:"redacted,omitempty"`  // Redacted lists the secret keys removed on export
+	Encrypted []string `json:"encrypted,omitempty"` // Encrypted lists the secret keys encrypted on export
+}
+
+// bundleFile is a profile read from a bundle.
+type bundleFile struct {
+	Profile
+	Ext     string
+	Content []byte
+}
+
+// write writes the manifest and files as a tar.gz archive to w.
+func write(w io.Writer, m Manifest, files []bundleFile) error {
+	manifest, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	add := func(name string, content []byte) error {
+		hdr := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(content)), ModTime: m.Created, Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(content)
+		return err
+	}
+	if err := add(ManifestFile, append(manifest, '\n')); err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := add(f.File, f.Content); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// read reads a bundle written by write and checks that its manifest matches
+// the profile files it contains.
+func read(r io.Reader, cli string) (Manifest, []bundleFile, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, nil, fmt.Errorf("not a bundle: %w", err)
+	}
+	defer func() { _ = gz.Close() }()
+
+	contents := map[string][]byte{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Manifest{}, nil, fmt.Errorf("not a bundle: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return Manifest{}, nil, fmt.Errorf("unexpected entry in bundle: %s", hdr.Name)
+		}
+		if hdr.Size > maxFileSize {
+			return Manifest{}, nil, fmt.Errorf("%s: file too large", hdr.Name)
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return Manifest{}, nil, err
+		}
+		contents[hdr.Name] = b
+	}
+
+	var m Manifest
+	raw, ok := contents[ManifestFile]
+	if !ok {
+		return Manifest{}, nil, fmt.Errorf("not a bundle: missing %s", ManifestFile)
+	}
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return Manifest{}, nil, fmt.Errorf("%s: %w", ManifestFile, err)
+	}
+	if m.FormatVersion > FormatVersion {
+		return Manifest{}, nil, fmt.Errorf("bundle format version %d is newer than the supported version %d", m.FormatVersion, FormatVersion)
+	}
+	if cli != "" && m.CLI != cli {
+		return Manifest{}, nil, fmt.Errorf("bundle was exported by %q, not %q", m.CLI, cli)
+	}
+
+	files := make([]bundleFile, 0, len(m.Profiles))
+	seen := map[string]bool{}
+	for _, p := range m.Profiles {
+		if err := profile.ValidateName(p.Name); err != nil {
+			return Manifest{}, nil, err
+		}
+		ext := strings.TrimPrefix(path.Ext(p.File), ".")
+		if p.File != profilesDir+p.Name+"."+ext || configure.FormatFromPath(p.File) == "" {
+			return Manifest{}, nil, fmt.Errorf("invalid file for profile %s: %s", p.Name, p.File)
+		}
+		if seen[p.Name] {
+			return Manifest{}, nil, fmt.Errorf("duplicate profile in bundle: %s", p.Name)
+		}
+		seen[p.Name] = true
+		content, ok := contents[p.File]
+		if !ok {
+			return Manifest{}, nil, fmt.Errorf("missing file in bundle: %s", p.File)
+		}
+		files = append(files, bundleFile{Profile: p, Ext: ext, Content: content})
+	}
+	return m, files, nil
+}
@@ -0,0 +1,257 @@
+package bundle
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/secret"
+)
+
+func writeProfiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func profileOptions(dir string) profile.Options {
+	return profile.Options{Dir: dir, Ext: "yaml", Exts: []string{"yaml", "yml", "json", "toml", "env"}}
+}
+
+func export(t *testing.T, dir string, opts ExportOptions) []byte {
+	t.Helper()
+	var out bytes.Buffer
+	opts.Profile = profileOptions(dir)
+	opts.Secrets = []string{"client-secret"}
+	opts.Manifest = Manifest{CLI: "mycli", SchemaVersion: 2}
+	opts.Output = &out
+	if err := Export(opts); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	return out.Bytes()
+}
+
+func TestExport_Redact(t *testing.T) {
+	dir := t.TempDir()
+	writeProfiles(t, dir, map[string]string{
+		"default.yaml": "client-id: a\nclient-secret: s3cr3t\n",
+		"dev.json":     `{"client-id": "b"}`,
+		"ref.yaml":     "client-secret: env:SECRET\n",
+	})
+
+	m, files, err := read(bytes.NewReader(export(t, dir, ExportOptions{Redact: true})), "mycli")
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if m.FormatVersion != FormatVersion || m.SchemaVersion != 2 || !m.Redacted || len(files) != 3 {
+		t.Fatalf("unexpected manifest: %+v", m)
+	}
+	for _, f := range files {
+		switch f.Name {
+		case "default":
+			if strings.Contains(string(f.Content), "s3cr3t") || len(f.Redacted) != 1 {
+				t.Errorf("expected client-secret to be redacted: %+v %q", f.Profile, f.Content)
+			}
+		case "dev":
+			if f.File != "profiles/dev.json" || string(f.Content) != `{"client-id": "b"}` {
+				t.Errorf("expected dev.json unchanged, got %s %q", f.File, f.Content)
+			}
+		case "ref":
+			if string(f.Content) != "client-secret: env:SECRET\n" || len(f.Redacted) != 0 {
+				t.Errorf("expected reference to be kept, got %q", f.Content)
+			}
+		}
+	}
+}
+
+func TestExport_Encrypt(t *testing.T) {
+	dir := t.TempDir()
+	writeProfiles(t, dir, map[string]string{"default.yaml": "client-secret: s3cr3t\n"})
+
+	err := Export(ExportOptions{Profile: profileOptions(dir), Secrets: []string{"client-secret"}, Output: &bytes.Buffer{}})
+	if !errors.Is(err, ErrPlaintextSecret) {
+		t.Fatalf("expected ErrPlaintextSecret without a passphrase, got %v", err)
+	}
+
+	_, files, err := read(bytes.NewReader(export(t, dir, ExportOptions{Passphrase: "pw"})), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(files[0].Content)
+	if strings.Contains(content, "s3cr3t") || !strings.Contains(content, "enc:") || len(files[0].Encrypted) != 1 {
+		t.Errorf("expected client-secret to be encrypted, got %q", content)
+	}
+}
+
+func TestExport_SelectedProfiles(t *testing.T) {
+	dir := t.TempDir()
+	writeProfiles(t, dir, map[string]string{"default.yaml": "a: 1\n", "dev.yaml": "a: 2\n"})
+
+	_, files, err := read(bytes.NewReader(export(t, dir, ExportOptions{Profiles: []string{"dev"}})), "")
+	if err != nil || len(files) != 1 || files[0].Name != "dev" {
+		t.Fatalf("expected only dev, got %+v, %v", files, err)
+	}
+
+	err = Export(ExportOptions{Profile: profileOptions(dir), Profiles: []string{"prod"}, Output: &bytes.Buffer{}})
+	if err == nil || !strings.Contains(err.Error(), "profile not found: prod") {
+		t.Errorf("expected profile not found error, got %v", err)
+	}
+}
+
+func TestImport_Modes(t *testing.T) {
+	src := t.TempDir()
+	writeProfiles(t, src, map[string]string{
+		"default.yaml": "client-id: new\ncommon:\n  var1: x\n",
+		"dev.yaml":     "client-id: dev\n",
+	})
+	b := export(t, src, ExportOptions{})
+
+	dst := t.TempDir()
+	writeProfiles(t, dst, map[string]string{"default.json": `{"client-id": "local"}`})
+	opts := ImportOptions{Profile: profileOptions(dst), CLI: "mycli", ErrOutput: &bytes.Buffer{}}
+
+	var out bytes.Buffer
+	opts.Output = &out
+	if err := Import(bytes.NewReader(b), opts); err == nil {
+		t.Fatal("expected an error for an existing profile")
+	}
+	if out.String() != "conflict: default: profile already exists\n" {
+		t.Errorf("unexpected report: %q", out.String())
+	}
+	if profile.Exists("dev", opts.Profile) {
+		t.Error("expected nothing to be written on conflict")
+	}
+
+	out.Reset()
+	opts.Mode = ModeMerge
+	if err := Import(bytes.NewReader(b), opts); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if out.String() != "conflict: default: client-id (kept local value)\n" {
+		t.Errorf("unexpected report: %q", out.String())
+	}
+	got, _ := os.ReadFile(filepath.Join(dst, "default.json"))
+	if !strings.Contains(string(got), `"local"`) || !strings.Contains(string(got), `"var1": "x"`) {
+		t.Errorf("expected merged default.json, got %s", got)
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "dev.yaml")); string(got) != "client-id: dev\n" {
+		t.Errorf("expected imported dev.yaml, got %q", got)
+	}
+
+	opts.Mode = ModeReplace
+	if err := Import(bytes.NewReader(b), opts); err != nil {
+		t.Fatalf("replace failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, "default.json")); !os.IsNotExist(err) {
+		t.Error("expected default.json to be replaced by default.yaml")
+	}
+	if got, _ := os.ReadFile(filepath.Join(dst, "default.yaml")); !strings.Contains(string(got), "client-id: new") {
+		t.Errorf("expected replaced default.yaml, got %q", got)
+	}
+}
+
+// TestWriteProfile_CreateConflict verifies that ModeCreate never merges
+// into a profile created after Import checked for conflicts.
+func TestWriteProfile_CreateConflict(t *testing.T) {
+	dir := t.TempDir()
+	writeProfiles(t, dir, map[string]string{"dev.yaml": "client-id: local\n"})
+
+	var out bytes.Buffer
+	opts := ImportOptions{Profile: profileOptions(dir), Output: &out, ErrOutput: &bytes.Buffer{}}
+	f := bundleFile{Profile: Profile{Name: "dev", File: "dev.yaml"}, Ext: "yaml", Content: []byte("common:\n  var1: x\n")}
+	data := map[string]interface{}{"common": map[string]interface{}{"var1": "x"}}
+	if err := writeProfile(filepath.Join(dir, "dev.yaml"), f, data, opts); err == nil {
+		t.Fatal("expected a conflict error")
+	}
+	if out.String() != "conflict: dev: profile already exists\n" {
+		t.Errorf("unexpected report: %q", out.String())
+	}
+	if got, _ := os.ReadFile(filepath.Join(dir, "dev.yaml")); string(got) != "client-id: local\n" {
+		t.Errorf("expected dev.yaml to be unchanged, got %q", got)
+	}
+}
+
+func TestImport_ReportsRedacted(t *testing.T) {
+	src := t.TempDir()
+	writeProfiles(t, src, map[string]string{"dev.yaml": "client-secret: s3cr3t\n"})
+	b := export(t, src, ExportOptions{Redact: true})
+
+	var errOut bytes.Buffer
+	opts := ImportOptions{Profile: profileOptions(t.TempDir()), Output: &bytes.Buffer{}, ErrOutput: &errOut}
+	if err := Import(bytes.NewReader(b), opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(errOut.String(), "redacted secret(s) to set again: client-secret") {
+		t.Errorf("expected redacted keys to be reported, got %q", errOut.String())
+	}
+}
+
+func TestImport_RejectsNewerSchema(t *testing.T) {
+	src := t.TempDir()
+	writeProfiles(t, src, map[string]string{"dev.yaml": "client-id: dev\n"})
+	b := export(t, src, ExportOptions{})
+
+	dst := t.TempDir()
+	opts := ImportOptions{Profile: profileOptions(dst), SchemaVersion: 1, Output: &bytes.Buffer{}, ErrOutput: &bytes.Buffer{}}
+	if err := Import(bytes.NewReader(b), opts); err == nil || !strings.Contains(err.Error(), "schema-version 2") {
+		t.Errorf("expected a newer schema error, got %v", err)
+	}
+	if profile.Exists("dev", opts.Profile) {
+		t.Error("expected nothing to be imported")
+	}
+
+	opts.SchemaVersion = 2
+	if err := Import(bytes.NewReader(b), opts); err != nil {
+		t.Errorf("Import failed: %v", err)
+	}
+}
+
+func TestRead_Invalid(t *testing.T) {
+	tests := map[string]Manifest{
+		"newer format":  {FormatVersion: FormatVersion + 1, CLI: "mycli"},
+		"other cli":     {FormatVersion: FormatVersion, CLI: "other"},
+		"path escape":   {FormatVersion: FormatVersion, CLI: "mycli", Profiles: []Profile{{Name: "dev", File: "../dev.yaml"}}},
+		"invalid name":  {FormatVersion: FormatVersion, CLI: "mycli", Profiles: []Profile{{Name: "../x", File: "profiles/../x.yaml"}}},
+		"unknown ext":   {FormatVersion: FormatVersion, CLI: "mycli", Profiles: []Profile{{Name: "dev", File: "profiles/dev.txt"}}},
+		"missing files": {FormatVersion: FormatVersion, CLI: "mycli", Profiles: []Profile{{Name: "dev", File: "profiles/dev.yaml"}}},
+	}
+	for name, m := range tests {
+		t.Run(name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := write(&b, m, nil); err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := read(&b, "mycli"); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	if _, _, err := read(strings.NewReader("not a bundle"), ""); err == nil {
+		t.Error("expected an error for a non-gzip input")
+	}
+}
+
+func TestExport_KeepsEncrypted(t *testing.T) {
+	env, err := secret.Encrypt("s3cr3t", "pw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	writeProfiles(t, dir, map[string]string{"default.yaml": "client-secret: " + env + "\n"})
+
+	_, files, err := read(bytes.NewReader(export(t, dir, ExportOptions{})), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(files[0].Content), env) || len(files[0].Encrypted) != 0 {
+		t.Errorf("expected the envelope to be kept, got %q", files[0].Content)
+	}
+}
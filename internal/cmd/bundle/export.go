@@ -0,0 +1,123 @@
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/secret"
+)
+
+// ErrPlaintextSecret is returned by Export when a secret value is stored in
+// plain text and neither a passphrase nor redaction is requested.
+var ErrPlaintextSecret = errors.New("secret is not encrypted")
+
+// ExportOptions represents the configuration for the config export command.
+type ExportOptions struct {
+	Profile    profile.Options // Profile locates the profile files
+	Profiles   []string        // Profiles lists the profiles to export; every profile when empty
+	Keys       []string        // Keys lists the dotted keys used to decode dotenv files
+	Secrets    []string        // Secrets lists the dotted keys holding secret values
+	Redact     bool            // Redact removes secret values instead of encrypting them
+	Passphrase string          // Passphrase encrypts plaintext secret values unless Redact is set
+	Manifest   Manifest        // Manifest provides the CLI and schema fields of the written manifest
+	Output     io.Writer       // Output receives the bundle
+}
+
+// Export writes the selected profiles to opts.Output as a bundle. Secret
+// values are removed when opts.Redact is set; otherwise plaintext values are
+// encrypted with opts.Passphrase, and values that are already encrypted or
+// that reference an external source (see secret.IsReference) are kept as is.
+// A file without plaintext secrets is stored byte for byte.
+func Export(opts ExportOptions) error {
+	names := opts.Profiles
+	if len(names) == 0 {
+		var err error
+		if names, err = profile.Names(opts.Profile); err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no profiles to export in %s", opts.Profile.Dir)
+		}
+	}
+
+	m := opts.Manifest
+	m.FormatVersion = FormatVersion
+	m.Created = time.Now().UTC().Truncate(time.Second)
+	m.Redacted = opts.Redact
+	m.Profiles = nil
+
+	files := make([]bundleFile, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		f, err := exportProfile(name, opts)
+		if err != nil {
+			return err
+		}
+		m.Profiles = append(m.Profiles, f.Profile)
+		files = append(files, f)
+	}
+	return write(opts.Output, m, files)
+}
+
+// exportProfile reads the file of the named profile and redacts or encrypts
+// its secret values.
+func exportProfile(name string, opts ExportOptions) (bundleFile, error) {
+	if err := profile.ValidateName(name); err != nil {
+		return bundleFile{}, err
+	}
+	path := profile.Path(name, opts.Profile)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return bundleFile{}, fmt.Errorf("profile not found: %s", name)
+		}
+		return bundleFile{}, err
+	}
+	format := configure.FormatFromPath(path)
+	data, err := configure.Unmarshal(content, format, opts.Keys)
+	if err != nil {
+		return bundleFile{}, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	ext := filepath.Ext(path)
+	f := bundleFile{Profile: Profile{Name: name, File: profilesDir + name + ext}, Ext: ext[1:]}
+	for _, key := range opts.Secrets {
+		v, _ := config.GetValue(data, key)
+		s, ok := v.(string)
+		if !ok || s == "" || secret.IsReference(s) {
+			continue
+		}
+		switch {
+		case opts.Redact:
+			config.UnsetValue(data, key)
+			f.Redacted = append(f.Redacted, key)
+		case secret.IsEncrypted(s):
+		case opts.Passphrase == "":
+			return bundleFile{}, fmt.Errorf("%s: %s: %w", name, key, ErrPlaintextSecret)
+		default:
+			if err := config.SetSecret(data, key, s, opts.Passphrase); err != nil {
+				return bundleFile{}, fmt.Errorf("%s: %s: %w", name, key, err)
+			}
+			f.Encrypted = append(f.Encrypted, key)
+		}
+	}
+
+	if len(f.Redacted) > 0 || len(f.Encrypted) > 0 {
+		if content, err = configure.Marshal(data, format); err != nil {
+			return bundleFile{}, err
+		}
+	}
+	f.Content = content
+	return f, nil
+}
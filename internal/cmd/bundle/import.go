@@ -0,0 +1,152 @@
+package bundle
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/configfile"
+)
+
+// Import modes.
+const (
+	ModeCreate  = ""        // ModeCreate imports only when no profile of the bundle exists
+	ModeMerge   = "merge"   // ModeMerge adds missing keys to existing profiles and keeps local values
+	ModeReplace = "replace" // ModeReplace overwrites existing profiles
+)
+
+// ImportOptions represents the configuration for the config import command.
+type ImportOptions struct {
+	Profile       profile.Options // Profile locates the profile files
+	Mode          string          // Mode is one of ModeCreate, ModeMerge and ModeReplace
+	Keys          []string        // Keys lists the dotted keys used to decode dotenv files
+	CLI           string          // CLI is the expected manifest CLI name; not checked when empty
+	SchemaVersion int             // SchemaVersion is the newest config schema version accepted; not checked when 0
+	Output        io.Writer       // Output is the standard output stream for the conflict report
+	ErrOutput     io.Writer       // ErrOutput is the error output stream for messages
+}
+
+// Import restores the profiles of the bundle read from r into
+// opts.Profile.Dir. Conflicts with existing profiles are written to
+// opts.Output, one "conflict: <profile>: <detail>" line each. In ModeCreate
+// any conflict aborts the import before a file is written; in ModeMerge a
+// conflict is a key whose local value differs and is kept. Secret keys
+// redacted on export are reported so that they can be set again. Bundles
+// exported for a config schema newer than opts.SchemaVersion are rejected.
+func Import(r io.Reader, opts ImportOptions) error {
+	m, files, err := read(r, opts.CLI)
+	if err != nil {
+		return err
+	}
+	if opts.SchemaVersion > 0 && m.SchemaVersion > opts.SchemaVersion {
+		return fmt.Errorf("bundle schema-version %d is newer than the supported version %d", m.SchemaVersion, opts.SchemaVersion)
+	}
+	switch opts.Mode {
+	case ModeCreate, ModeMerge, ModeReplace:
+	default:
+		return fmt.Errorf("unsupported import mode: %s", opts.Mode)
+	}
+
+	incoming := make([]map[string]interface{}, len(files))
+	for i, f := range files {
+		data, err := configure.Unmarshal(f.Content, configure.FormatFromPath(f.File), opts.Keys)
+		if err != nil {
+			return fmt.Errorf("parse %s: %w", f.File, err)
+		}
+		incoming[i] = data
+	}
+
+	if opts.Mode == ModeCreate {
+		var existing []string
+		for _, f := range files {
+			if profile.Exists(f.Name, opts.Profile) {
+				existing = append(existing, f.Name)
+				fmt.Fprintf(opts.Output, "conflict: %s: profile already exists\n", f.Name)
+			}
+		}
+		if len(existing) > 0 {
+			return fmt.Errorf("%d profile(s) already exist: %s (use --merge or --replace)", len(existing), strings.Join(existing, ", "))
+		}
+	}
+
+	for i, f := range files {
+		if err := importProfile(f, incoming[i], opts); err != nil {
+			return err
+		}
+		if len(f.Redacted) > 0 {
+			fmt.Fprintf(opts.ErrOutput, "Profile %s: redacted secret(s) to set again: %s\n", f.Name, strings.Join(f.Redacted, ", "))
+		}
+	}
+	return nil
+}
+
+// importProfile writes one profile of a bundle according to opts.Mode while
+// holding its lock (see configure.Lock).
+func importProfile(f bundleFile, data map[string]interface{}, opts ImportOptions) error {
+	target := filepath.Join(opts.Profile.Dir, f.Name+"."+f.Ext)
+	return configure.WithLock(target, func() error {
+		return writeProfile(target, f, data, opts)
+	})
+}
+
+// writeProfile writes one profile of a bundle to target according to
+// opts.Mode. In ModeCreate, a profile created since Import checked for
+// conflicts is reported as one and never merged.
+func writeProfile(target string, f bundleFile, data map[string]interface{}, opts ImportOptions) error {
+	if !profile.Exists(f.Name, opts.Profile) {
+		if err := configfile.WriteFile(target, f.Content); err != nil {
+			return err
+		}
+		fmt.Fprintln(opts.ErrOutput, "Imported profile:", f.Name)
+		return nil
+	}
+	if opts.Mode == ModeCreate {
+		fmt.Fprintf(opts.Output, "conflict: %s: profile already exists\n", f.Name)
+		return fmt.Errorf("profile %s was created during the import (use --merge or --replace)", f.Name)
+	}
+
+	existing := profile.Path(f.Name, opts.Profile)
+	if opts.Mode == ModeReplace {
+		if err := configfile.WriteFile(target, f.Content); err != nil {
+			return err
+		}
+		if existing != target {
+			if err := configfile.Remove(existing); err != nil {
+				return err
+			}
+		}
+		fmt.Fprintln(opts.ErrOutput, "Replaced profile:", f.Name)
+		return nil
+	}
+
+	b, err := os.ReadFile(existing)
+	if err != nil {
+		return err
+	}
+	local, err := configure.Unmarshal(b, configure.FormatFromPath(existing), opts.Keys)
+	if err != nil {
+		return fmt.Errorf("parse %s: %w", existing, err)
+	}
+	added := 0
+	for _, c := range config.Changes(local, data) {
+		switch c.Type {
+		case config.ChangeAdded:
+			config.SetValue(local, c.Key, c.New)
+			added++
+		case config.ChangeChanged:
+			fmt.Fprintf(opts.Output, "conflict: %s: %s (kept local value)\n", f.Name, c.Key)
+		}
+	}
+	if added > 0 {
+		if err := configure.WriteFile(existing, local, configure.FormatFromPath(existing)); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(opts.ErrOutput, "Merged profile: %s (%d key(s) added)\n", f.Name, added)
+	return nil
+}
@@ -3,12 +3,27 @@ package cat
 import (
 	"fmt"
 	"io"
-	"os"
+
+	"github.com/rising3/go-cli/internal/stdio"
 )
 
 // CatFunc is the internal cat implementation (can be mocked in tests)
-var CatFunc = func(filenames []string, opts Options) error {
-	return catImpl(filenames, opts, os.Stdin, os.Stdout, os.Stderr)
+var CatFunc = Cat
+
+// Cat concatenates the files to opts.Output, reading opts.Input when no file
+// is given. Streams left nil default to the process' stdio.
+func Cat(filenames []string, opts Options) error {
+	streams := stdio.NewDefault()
+	if opts.Input != nil {
+		streams.In = opts.Input
+	}
+	if opts.Output != nil {
+		streams.Out = opts.Output
+	}
+	if opts.ErrOutput != nil {
+		streams.Err = opts.ErrOutput
+	}
+	return catImpl(filenames, opts, streams.In, streams.Out, streams.Err)
 }
 
 // catImpl is the actual implementation that can be tested
@@ -1,6 +1,10 @@
 package cat
 
-import "github.com/spf13/cobra"
+import (
+	"io"
+
+	"github.com/spf13/cobra"
+)
 
 // Options holds the formatting options for cat command
 type Options struct {
@@ -18,6 +22,15 @@ type Options struct {
 
 	// ShowNonPrinting uses ^ and M- notation for control characters (-v flag)
 	ShowNonPrinting bool
+
+	// Input is read when no file is given; os.Stdin when nil
+	Input io.Reader
+
+	// Output receives the concatenated files; os.Stdout when nil
+	Output io.Writer
+
+	// ErrOutput receives the errors of files that cannot be read; os.Stderr when nil
+	ErrOutput io.Writer
 }
 
 // NewOptions creates Options from Cobra command flags
@@ -0,0 +1,150 @@
+// Package config implements the business logic of the `config` subcommands,
+// which read and modify individual keys of a profile configuration file.
+package config
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Options represents the I/O streams shared by the config subcommands.
+type Options struct {
+	Output    io.Writer // Output is the standard output stream for values
+	ErrOutput io.Writer // ErrOutput is the error output stream for messages
+}
+
+// Get writes the value stored under the dotted key in data to opts.Output.
+// Scalars are printed as-is; sections are printed as YAML.
+// It returns an error if the key is not present.
+func Get(data map[string]interface{}, key string, opts Options) error {
+	v, ok := GetValue(data, key)
+	if !ok {
+		return fmt.Errorf("key not found: %s", key)
+	}
+	if m, isMap := v.(map[string]interface{}); isMap {
+		out, err := yaml.Marshal(m)
+		if err != nil {
+			return err
+		}
+		_, err = opts.Output.Write(out)
+		return err
+	}
+	_, err := fmt.Fprintln(opts.Output, FormatValue(v))
+	return err
+}
+
+// List writes every leaf key of data as sorted "key=value" lines to opts.Output.
+func List(data map[string]interface{}, opts Options) error {
+	for _, k := range Keys(data) {
+		v, _ := GetValue(data, k)
+		if _, err := fmt.Fprintf(opts.Output, "%s=%s\n", k, FormatValue(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetValue returns the value stored under the dotted key in data.
+func GetValue(data map[string]interface{}, key string) (interface{}, bool) {
+	var cur interface{} = data
+	for _, part := range splitKey(key) {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// SetValue stores value under the dotted key in data, creating intermediate
+// sections as needed. A scalar found on the path is replaced by a section.
+func SetValue(data map[string]interface{}, key string, value interface{}) {
+	parts := splitKey(key)
+	cur := data
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := cur[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			cur[part] = next
+		}
+		cur = next
+	}
+	cur[parts[len(parts)-1]] = value
+}
+
+// UnsetValue removes the dotted key from data and prunes sections left empty.
+// It reports whether the key was present.
+func UnsetValue(data map[string]interface{}, key string) bool {
+	return unset(data, splitKey(key))
+}
+
+// unset removes parts from m recursively, deleting emptied sections on the way back.
+func unset(m map[string]interface{}, parts []string) bool {
+	if len(parts) == 1 {
+		if _, ok := m[parts[0]]; !ok {
+			return false
+		}
+		delete(m, parts[0])
+		return true
+	}
+	child, ok := m[parts[0]].(map[string]interface{})
+	if !ok || !unset(child, parts[1:]) {
+		return false
+	}
+	if len(child) == 0 {
+		delete(m, parts[0])
+	}
+	return true
+}
+
+// Keys returns the sorted dotted keys of every leaf value in data.
+func Keys(data map[string]interface{}) []string {
+	var keys []string
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+				walk(key, child)
+				continue
+			}
+			keys = append(keys, key)
+		}
+	}
+	walk("", data)
+	sort.Strings(keys)
+	return keys
+}
+
+// FormatValue renders a scalar or list value for single-line output.
+func FormatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = FormatValue(item)
+		}
+		return strings.Join(parts, ",")
+	case []string:
+		return strings.Join(val, ",")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// splitKey lower-cases key and splits it on dots, matching viper's key handling.
+func splitKey(key string) []string {
+	return strings.Split(strings.ToLower(key), ".")
+}
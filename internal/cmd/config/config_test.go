@@ -0,0 +1,91 @@
+package config_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+)
+
+func newData() map[string]interface{} {
+	return map[string]interface{}{
+		"client-id": "abc",
+		"common": map[string]interface{}{
+			"var1": "hello",
+			"var2": 123,
+		},
+	}
+}
+
+func TestGet_Scalar(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.Get(newData(), "common.var2", config.Options{Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "123\n" {
+		t.Errorf("output = %q, want %q", out.String(), "123\n")
+	}
+}
+
+func TestGet_SectionPrintsYAML(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.Get(newData(), "common", config.Options{Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "var1: hello\nvar2: 123\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestGet_MissingKey(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.Get(newData(), "common.var3", config.Options{Output: &out}); err == nil {
+		t.Fatal("expected error for missing key")
+	}
+}
+
+func TestList_SortedKeyValues(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.List(newData(), config.Options{Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "client-id=abc\ncommon.var1=hello\ncommon.var2=123\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestSetValue_CreatesSections(t *testing.T) {
+	data := map[string]interface{}{}
+	config.SetValue(data, "Hoge.Foo.Bar", "x")
+
+	want := map[string]interface{}{
+		"hoge": map[string]interface{}{
+			"foo": map[string]interface{}{"bar": "x"},
+		},
+	}
+	if !reflect.DeepEqual(data, want) {
+		t.Errorf("data = %v, want %v", data, want)
+	}
+}
+
+func TestUnsetValue_PrunesEmptySections(t *testing.T) {
+	data := map[string]interface{}{
+		"hoge": map[string]interface{}{
+			"foo": map[string]interface{}{"bar": "x"},
+		},
+		"client-id": "abc",
+	}
+
+	if !config.UnsetValue(data, "hoge.foo.bar") {
+		t.Fatal("expected key to be removed")
+	}
+	if _, ok := data["hoge"]; ok {
+		t.Errorf("expected empty hoge section to be pruned, got %v", data)
+	}
+	if config.UnsetValue(data, "hoge.foo.bar") {
+		t.Error("expected second unset to report missing key")
+	}
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Change types reported by Changes.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change is a key whose value differs between two configurations.
+type Change struct {
+	Key  string      `json:"key"`
+	Type string      `json:"type"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// DiffOptions represents the configuration for the config diff command.
+type DiffOptions struct {
+	AName  string                // AName is the name of the first configuration in the output
+	BName  string                // BName is the name of the second configuration in the output
+	Format string                // Format is the output format ("text" or "json")
+	Secret func(key string) bool // Secret reports keys whose values are masked; nothing is masked when nil
+	Output io.Writer             // Output is the standard output stream
+}
+
+// Changes returns the leaf keys added, removed or changed from a to b,
+// sorted by key. Values are compared by their single-line rendering, so
+// that the same value read from files of different formats (such as 5 from
+// YAML and "5" from dotenv) is not reported.
+func Changes(a, b map[string]interface{}) []Change {
+	keys := map[string]bool{}
+	for _, k := range Keys(a) {
+		keys[k] = true
+	}
+	for _, k := range Keys(b) {
+		keys[k] = true
+	}
+
+	changes := []Change{}
+	for _, k := range sortedKeys(keys) {
+		oldValue, inA := GetValue(a, k)
+		newValue, inB := GetValue(b, k)
+		switch {
+		case !inA:
+			changes = append(changes, Change{Key: k, Type: ChangeAdded, New: newValue})
+		case !inB:
+			changes = append(changes, Change{Key: k, Type: ChangeRemoved, Old: oldValue})
+		case FormatValue(oldValue) != FormatValue(newValue):
+			changes = append(changes, Change{Key: k, Type: ChangeChanged, Old: oldValue, New: newValue})
+		}
+	}
+	return changes
+}
+
+// Diff writes the key-level changes from a to b to opts.Output. The text
+// format resembles a unified diff: a header naming both sides, then a
+// "-key: value" line for every removed or changed old value and a
+// "+key: value" line for every added or changed new value. The JSON format
+// is an object with both names and the list of changes. Values of secret
+// keys are replaced by Mask, which still reveals that they changed.
+func Diff(a, b map[string]interface{}, opts DiffOptions) error {
+	changes := Changes(a, b)
+	if opts.Secret != nil {
+		for i, c := range changes {
+			if opts.Secret(c.Key) {
+				changes[i].Old, changes[i].New = maskValue(c.Old), maskValue(c.New)
+			}
+		}
+	}
+
+	switch opts.Format {
+	case "", "text":
+		return diffText(changes, opts)
+	case "json":
+		out, err := json.MarshalIndent(struct {
+			A       string   `json:"a"`
+			B       string   `json:"b"`
+			Changes []Change `json:"changes"`
+		}{opts.AName, opts.BName, changes}, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(opts.Output, string(out))
+		return err
+	default:
+		return fmt.Errorf("unsupported output format: %s (want text or json)", opts.Format)
+	}
+}
+
+// diffText writes changes in the text format; nothing is written when there
+// are no changes.
+func diffText(changes []Change, opts DiffOptions) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", opts.AName, opts.BName)
+	for _, c := range changes {
+		if c.Type != ChangeAdded {
+			fmt.Fprintf(&sb, "-%s: %s\n", c.Key, FormatValue(c.Old))
+		}
+		if c.Type != ChangeRemoved {
+			fmt.Fprintf(&sb, "+%s: %s\n", c.Key, FormatValue(c.New))
+		}
+	}
+	_, err := io.WriteString(opts.Output, sb.String())
+	return err
+}
+
+// maskValue returns Mask for non-empty values and v otherwise.
+func maskValue(v interface{}) interface{} {
+	if FormatValue(v) == "" {
+		return v
+	}
+	return Mask
+}
+
+// sortedKeys returns the keys of set in ascending order.
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
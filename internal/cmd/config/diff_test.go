@@ -0,0 +1,110 @@
+package config_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+)
+
+func diffInputs() (map[string]interface{}, map[string]interface{}) {
+	a := map[string]interface{}{
+		"client-id":     "dev",
+		"client-secret": "s1",
+		"common":        map[string]interface{}{"var1": "x", "var2": 5},
+	}
+	b := map[string]interface{}{
+		"client-id":     "prod",
+		"client-secret": "s2",
+		"common":        map[string]interface{}{"var2": "5"},
+		"hoge":          map[string]interface{}{"fuga": "new"},
+	}
+	return a, b
+}
+
+func isSecret(key string) bool { return key == "client-secret" }
+
+func TestChanges(t *testing.T) {
+	a, b := diffInputs()
+	got := config.Changes(a, b)
+	want := []config.Change{
+		{Key: "client-id", Type: config.ChangeChanged, Old: "dev", New: "prod"},
+		{Key: "client-secret", Type: config.ChangeChanged, Old: "s1", New: "s2"},
+		{Key: "common.var1", Type: config.ChangeRemoved, Old: "x"},
+		{Key: "hoge.fuga", Type: config.ChangeAdded, New: "new"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Changes = %+v; want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %+v; want %+v", i, got[i], want[i])
+		}
+	}
+	if len(config.Changes(a, a)) != 0 {
+		t.Error("expected no changes between equal configurations")
+	}
+}
+
+func TestDiff_TextMasksSecrets(t *testing.T) {
+	a, b := diffInputs()
+	var out bytes.Buffer
+	opts := config.DiffOptions{AName: "dev", BName: "prod", Secret: isSecret, Output: &out}
+	if err := config.Diff(a, b, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := `--- dev
++++ prod
+-client-id: dev
++client-id: prod
+-client-secret: ********
++client-secret: ********
+-common.var1: x
++hoge.fuga: new
+`
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+
+	out.Reset()
+	if err := config.Diff(a, a, opts); err != nil || out.Len() != 0 {
+		t.Errorf("expected no output for equal configurations, got %q, %v", out.String(), err)
+	}
+}
+
+func TestDiff_JSON(t *testing.T) {
+	a, b := diffInputs()
+	var out bytes.Buffer
+	if err := config.Diff(a, b, config.DiffOptions{AName: "dev", BName: "prod", Format: "json", Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var got struct {
+		A, B    string
+		Changes []config.Change
+	}
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out.String(), err)
+	}
+	if got.A != "dev" || got.B != "prod" || len(got.Changes) != 4 {
+		t.Fatalf("unexpected output: %s", out.String())
+	}
+	if c := got.Changes[1]; c.Key != "client-secret" || c.Old != "s1" || c.New != "s2" {
+		t.Errorf("expected unmasked secret without Secret, got %+v", c)
+	}
+
+	out.Reset()
+	if err := config.Diff(a, a, config.DiffOptions{Format: "json", Output: &out}); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(out.Bytes(), []byte(`"changes": []`)) {
+		t.Errorf("expected an empty change list, got %s", out.String())
+	}
+}
+
+func TestDiff_UnsupportedFormat(t *testing.T) {
+	a, b := diffInputs()
+	if err := config.Diff(a, b, config.DiffOptions{Format: "xml", Output: &bytes.Buffer{}}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/rising3/go-cli/internal/secret"
+)
+
+// SetSecret encrypts value with passphrase and stores the envelope under the
+// dotted key in data.
+func SetSecret(data map[string]interface{}, key, value, passphrase string) error {
+	env, err := secret.Encrypt(value, passphrase)
+	if err != nil {
+		return err
+	}
+	SetValue(data, key, env)
+	return nil
+}
+
+// RotateKeys re-encrypts every encrypted value in data, decrypting it with
+// oldPassphrase and encrypting it with newPassphrase. It returns the rotated
+// keys. data is left unchanged if any value cannot be decrypted.
+func RotateKeys(data map[string]interface{}, oldPassphrase, newPassphrase string) ([]string, error) {
+	rotated := map[string]string{}
+	for _, key := range Keys(data) {
+		v, _ := GetValue(data, key)
+		s, ok := v.(string)
+		if !ok || !secret.IsEncrypted(s) {
+			continue
+		}
+		plaintext, err := secret.Decrypt(s, oldPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		if rotated[key], err = secret.Encrypt(plaintext, newPassphrase); err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	keys := make([]string, 0, len(rotated))
+	for _, key := range Keys(data) {
+		if env, ok := rotated[key]; ok {
+			SetValue(data, key, env)
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,46 @@
+package config_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/secret"
+)
+
+func TestSetSecretAndRotateKeys(t *testing.T) {
+	oldIterations := secret.Iterations
+	secret.Iterations = 1000
+	t.Cleanup(func() { secret.Iterations = oldIterations })
+
+	data := map[string]interface{}{"client-id": "plain"}
+	if err := config.SetSecret(data, "client-secret", "s3cr3t", "old"); err != nil {
+		t.Fatalf("SetSecret failed: %v", err)
+	}
+	if err := config.SetSecret(data, "nested.token", "tok", "old"); err != nil {
+		t.Fatalf("SetSecret failed: %v", err)
+	}
+
+	before := data["client-secret"]
+	if _, err := config.RotateKeys(data, "wrong", "new"); !errors.Is(err, secret.ErrDecrypt) {
+		t.Fatalf("expected decrypt error, got: %v", err)
+	}
+	if data["client-secret"] != before {
+		t.Fatal("data changed by a failed rotation")
+	}
+
+	keys, err := config.RotateKeys(data, "old", "new")
+	if err != nil {
+		t.Fatalf("RotateKeys failed: %v", err)
+	}
+	if len(keys) != 2 || keys[0] != "client-secret" || keys[1] != "nested.token" {
+		t.Errorf("rotated keys = %v", keys)
+	}
+	got, err := secret.Decrypt(data["client-secret"].(string), "new")
+	if err != nil || got != "s3cr3t" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+	if data["client-id"] != "plain" {
+		t.Errorf("plain values must be left alone: %v", data["client-id"])
+	}
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Mask replaces the values of secret fields in show and diff output.
+const Mask = "********"
+
+// Entry is an effective configuration value together with its origin.
+type Entry struct {
+	Key    string      `json:"key" yaml:"key"`
+	Value  interface{} `json:"value" yaml:"value"`
+	Source string      `json:"source,omitempty" yaml:"source,omitempty"`
+	Path   string      `json:"path,omitempty" yaml:"path,omitempty"`
+}
+
+// ShowOptions represents the configuration for the config show command.
+type ShowOptions struct {
+	Origin bool      // Origin includes the source layer and path of every value
+	Format string    // Format is the output format ("yaml", "json" or "table")
+	Output io.Writer // Output is the standard output stream
+}
+
+// Show writes entries to opts.Output in opts.Format. Without Origin, YAML and
+// JSON output is a nested map of values; with Origin it is a list of entries.
+func Show(entries []Entry, opts ShowOptions) error {
+	switch opts.Format {
+	case "", "yaml", "yml":
+		out, err := yaml.Marshal(showData(entries, opts.Origin))
+		if err != nil {
+			return err
+		}
+		_, err = opts.Output.Write(out)
+		return err
+	case "json":
+		out, err := json.MarshalIndent(showData(entries, opts.Origin), "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(opts.Output, string(out))
+		return err
+	case "table":
+		return showTable(entries, opts)
+	default:
+		return fmt.Errorf("unsupported output format: %s (want yaml, json or table)", opts.Format)
+	}
+}
+
+// showData returns the value marshaled by the YAML and JSON formats.
+func showData(entries []Entry, origin bool) interface{} {
+	if origin {
+		return entries
+	}
+	data := map[string]interface{}{}
+	for _, e := range entries {
+		SetValue(data, e.Key, e.Value)
+	}
+	return data
+}
+
+// showTable writes entries as aligned columns.
+func showTable(entries []Entry, opts ShowOptions) error {
+	w := tabwriter.NewWriter(opts.Output, 0, 0, 2, ' ', 0)
+	if opts.Origin {
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE\tSOURCE\tPATH")
+	} else {
+		_, _ = fmt.Fprintln(w, "KEY\tVALUE")
+	}
+	for _, e := range entries {
+		if opts.Origin {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Key, FormatValue(e.Value), e.Source, e.Path)
+		} else {
+			_, _ = fmt.Fprintf(w, "%s\t%s\n", e.Key, FormatValue(e.Value))
+		}
+	}
+	return w.Flush()
+}
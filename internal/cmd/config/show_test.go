@@ -0,0 +1,57 @@
+package config_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+)
+
+func showEntries() []config.Entry {
+	return []config.Entry{
+		{Key: "client-secret", Value: config.Mask, Source: "profile:prod", Path: "/cfg/prod.yaml"},
+		{Key: "common.var2", Value: 5, Source: "env", Path: "MYCLI_COMMON_VAR2"},
+	}
+}
+
+func TestShow_YAMLNested(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.Show(showEntries(), config.ShowOptions{Format: "yaml", Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "client-secret: '********'\ncommon:\n    var2: 5\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestShow_JSONWithOrigin(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.Show(showEntries(), config.ShowOptions{Origin: true, Format: "json", Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"source": "env"`) || !strings.Contains(out.String(), `"path": "MYCLI_COMMON_VAR2"`) {
+		t.Errorf("expected origin in JSON output, got: %s", out.String())
+	}
+}
+
+func TestShow_TableWithOrigin(t *testing.T) {
+	var out bytes.Buffer
+	if err := config.Show(showEntries(), config.ShowOptions{Origin: true, Format: "table", Output: &out}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "KEY") {
+		t.Fatalf("unexpected table: %q", out.String())
+	}
+	if !strings.Contains(lines[2], "common.var2") || !strings.Contains(lines[2], "MYCLI_COMMON_VAR2") {
+		t.Errorf("unexpected row: %q", lines[2])
+	}
+}
+
+func TestShow_UnsupportedFormat(t *testing.T) {
+	if err := config.Show(nil, config.ShowOptions{Format: "xml", Output: &bytes.Buffer{}}); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rising3/go-cli/internal/schema"
+	"gopkg.in/yaml.v3"
+)
+
+// Location is the position of a value inside a config file.
+type Location struct {
+	File   string // File is the path of the config file, or the flag or environment variable setting the value
+	Line   int    // Line is the 1-based line number, or 0 if unknown
+	Column int    // Column is the 1-based column number, or 0 if unknown
+}
+
+// String formats the location as "file:line:col", omitting unknown parts.
+func (l Location) String() string {
+	if l.Line == 0 {
+		return l.File
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Column)
+}
+
+// Validate writes every violation to opts.Output, prefixed with the location
+// of the offending value as returned by locate, if any. It returns an error
+// when there is at least one violation.
+func Validate(violations []schema.Violation, locate func(key string) Location, opts Options) error {
+	if len(violations) == 0 {
+		if opts.ErrOutput != nil {
+			_, _ = fmt.Fprintln(opts.ErrOutput, "Configuration is valid")
+		}
+		return nil
+	}
+
+	for _, v := range violations {
+		prefix := ""
+		if loc := locate(v.Key); loc.File != "" {
+			prefix = loc.String() + ": "
+		}
+		if _, err := fmt.Fprintf(opts.Output, "%s%s: %s\n", prefix, v.Key, v.Message); err != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("configuration has %d violation(s)", len(violations))
+}
+
+// Locate returns the position of the value stored under the dotted key in the
+// YAML or JSON file at path.
+func Locate(path, key string) (Location, bool) {
+	doc, err := parseNode(path)
+	if err != nil {
+		return Location{}, false
+	}
+	return locateNode(doc, path, key)
+}
+
+// parseNode parses the file at path into a YAML document node.
+func parseNode(path string) (*yaml.Node, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// locateNode walks doc along the dotted key, matching mapping keys case-insensitively.
+func locateNode(doc *yaml.Node, path, key string) (Location, bool) {
+	node := doc
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return Location{}, false
+		}
+		node = node.Content[0]
+	}
+	for _, part := range splitKey(key) {
+		if node.Kind != yaml.MappingNode {
+			return Location{}, false
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if strings.EqualFold(node.Content[i].Value, part) {
+				next = node.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return Location{}, false
+		}
+		node = next
+	}
+	return Location{File: path, Line: node.Line, Column: node.Column}, true
+}
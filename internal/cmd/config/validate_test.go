@@ -0,0 +1,75 @@
+package config_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/schema"
+)
+
+func TestLocate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.yaml")
+	content := "client-id: abc\ncommon:\n  var1: x\n  var2: -1\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	loc, ok := config.Locate(path, "common.var2")
+	if !ok {
+		t.Fatal("expected key to be located")
+	}
+	if loc.Line != 4 || loc.Column != 9 {
+		t.Errorf("location = %d:%d, want 4:9", loc.Line, loc.Column)
+	}
+	if _, ok := config.Locate(path, "common.var3"); ok {
+		t.Error("expected missing key not to be located")
+	}
+}
+
+func TestValidate_ReportsLocations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prod.yaml")
+	if err := os.WriteFile(path, []byte("client-id: abc\ncommon:\n  var2: -2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	locate := func(key string) config.Location {
+		switch key {
+		case "common.var2":
+			loc, _ := config.Locate(path, key)
+			return loc
+		case "client-id":
+			return config.Location{File: "--client-id"}
+		}
+		return config.Location{}
+	}
+
+	violations := []schema.Violation{
+		{Key: "common.var2", Rule: "min", Message: "must be at least 0"},
+		{Key: "client-id", Rule: "min", Message: "is too short"},
+		{Key: "hoge.fuga", Rule: "required", Message: "is required"},
+	}
+	var out bytes.Buffer
+	err := config.Validate(violations, locate, config.Options{Output: &out})
+	if err == nil {
+		t.Fatal("expected error for violations")
+	}
+
+	want := path + ":3:9: common.var2: must be at least 0\n" +
+		"--client-id: client-id: is too short\n" +
+		"hoge.fuga: is required\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestValidate_NoViolations(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if err := config.Validate(nil, nil, config.Options{Output: &out, ErrOutput: &errOut}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no output, got %q", out.String())
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/rising3/go-cli/internal/configfile"
+	"github.com/rising3/go-cli/internal/fileperm"
 	proc "github.com/rising3/go-cli/internal/proc"
 	stdio "github.com/rising3/go-cli/internal/stdio"
 
@@ -27,7 +29,7 @@ type ConfigureOptions struct {
 // ConfigureFile ensures the config at target exists according to options.
 // It delegates small tasks to helpers for readability and testability.
 func ConfigureFile(target string, opts ConfigureOptions) error {
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+	if err := fileperm.MkdirAll(filepath.Dir(target)); err != nil {
 		return err
 	}
 
@@ -51,23 +53,22 @@ func writeConfigIfNeeded(target string, opts ConfigureOptions) error {
 		return nil
 	}
 
-	if opts.Force {
-		_ = os.Remove(target)
-	}
-
 	out, err := marshalData(opts.Data, opts.Format)
 	if err != nil {
 		return err
 	}
 
-	// use stdio.OpenWriter so callers can use "-" to write to stdout or a file path
-	w, closer, err := stdio.OpenWriterWithPerm(target, 0o644)
-	if err != nil {
-		return err
-	}
-	defer stdio.CloseAll(closer)
-
-	if _, err := w.Write(out); err != nil {
+	// "-" writes to stdout; files are replaced atomically, keeping a backup
+	if target == "-" {
+		w, closer, err := stdio.OpenWriter(target)
+		if err != nil {
+			return err
+		}
+		defer stdio.CloseAll(closer)
+		if _, err := w.Write(out); err != nil {
+			return err
+		}
+	} else if err := configfile.WriteFile(target, out); err != nil {
 		return err
 	}
 	if opts.Streams.Err != nil {
@@ -2,11 +2,18 @@ package configure
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
+	"github.com/pelletier/go-toml/v2"
+	"github.com/rising3/go-cli/internal/configfile"
+	"github.com/rising3/go-cli/internal/diff"
+	"github.com/rising3/go-cli/internal/fileperm"
 	"github.com/rising3/go-cli/internal/proc"
 	"gopkg.in/yaml.v3"
 )
@@ -14,20 +21,35 @@ import (
 // ConfigureOptions represents the configuration for the configure command.
 // It encapsulates all parameters needed to create and optionally edit a configuration file.
 type ConfigureOptions struct {
-	Force            bool                             // Force overwrites existing configuration files without prompting
-	Edit             bool                             // Edit launches an editor after creating the configuration file
-	NoWait           bool                             // NoWait runs the editor in background without blocking
-	Data             map[string]interface{}           // Data contains the configuration data to be serialized
-	Format           string                           // Format specifies the output format ("yaml", "yml", or "json")
-	Output           io.Writer                        // Output is the standard output stream (currently unused, reserved for future use)
-	ErrOutput        io.Writer                        // ErrOutput is the error output stream for messages
-	EditorLookup     func() (string, []string, error) // EditorLookup is a function that returns the editor command and arguments
-	EditorShouldWait func(string, []string) bool      // EditorShouldWait determines whether to wait for the editor to exit
+	Force            bool                              // Force overwrites existing configuration files without prompting
+	Edit             bool                              // Edit launches an editor after creating the configuration file
+	NoWait           bool                              // NoWait runs the editor in background without blocking
+	Data             map[string]interface{}            // Data contains the configuration data to be serialized
+	Overlays         []map[string]interface{}          // Overlays are merged over Data in order (see Merge) before it is serialized
+	Format           string                            // Format specifies the output format ("yaml", "yml", "json", "toml" or "dotenv")
+	Check            func() error                      // Check runs under the lock of target before the file is written; its error aborts Configure
+	Written          func() error                      // Written runs under the lock of target after the file is written
+	DryRun           bool                              // DryRun prints the file to Output instead of writing it
+	Diff             bool                              // Diff prints a unified diff against Current to Output instead of writing the file
+	Current          string                            // Current is the existing file compared by Diff; target when empty
+	Secret           func(key string) bool             // Secret reports keys whose values are masked on both sides of Diff; nothing is masked when nil
+	Input            io.Reader                         // Input is the standard input stream of the editor; os.Stdin when nil
+	Output           io.Writer                         // Output is the standard output stream for DryRun, Diff and the editor; os.Stdout for the editor when nil
+	ErrOutput        io.Writer                         // ErrOutput is the error output stream for messages and the editor; os.Stderr for the editor when nil
+	EditorLookup     func() (string, []string, error)  // EditorLookup is a function that returns the editor command and arguments
+	EditorShouldWait func(string, []string) bool       // EditorShouldWait determines whether to wait for the editor to exit
+	ExecCommand      func(string, ...string) *exec.Cmd // ExecCommand builds the editor process; proc.ExecCommand when nil
 }
 
 // Configure creates or overwrites a configuration file at the specified target path.
-// It marshals the opts.Data according to opts.Format and writes it to the file.
+// It merges opts.Overlays over opts.Data, marshals the result according to
+// opts.Format and writes it to the file.
 // If opts.Edit is true, it launches the configured editor after file creation.
+// With opts.DryRun or opts.Diff nothing is written (see preview).
+// The profile is locked (see Lock) from the existence check until the file
+// is written, with opts.Check and opts.Written running under the lock; the
+// lock is released before the editor starts. Callers must not hold the lock
+// themselves.
 //
 // Parameters:
 //   - target: Absolute path to the configuration file
@@ -37,77 +59,300 @@ type ConfigureOptions struct {
 //   - error: Returns error if file creation fails or editor launch fails
 //     (unless editor detection fails, in which case error is logged and nil is returned)
 func Configure(target string, opts ConfigureOptions) error {
+	if opts.DryRun || opts.Diff {
+		return preview(target, opts)
+	}
+
 	// T013: Create parent directory
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+	if err := fileperm.MkdirAll(filepath.Dir(target)); err != nil {
+		return err
+	}
+
+	// Hold the profile lock from the existence check until the file is
+	// written, so that nothing else writes the file in between
+	var wrote bool
+	err := WithLock(target, func() error {
+		var err error
+		wrote, err = write(target, opts)
+		return err
+	})
+	if err != nil || !wrote || !opts.Edit {
 		return err
 	}
+	return edit(target, opts)
+}
+
+// write writes the configuration file of Configure to target, which the
+// caller has locked, and reports whether it did: an existing file is kept
+// unless opts.Force is set.
+func write(target string, opts ConfigureOptions) (bool, error) {
+	if opts.Check != nil {
+		if err := opts.Check(); err != nil {
+			return false, err
+		}
+	}
 
 	// T015: Check if file exists
 	if _, err := os.Stat(target); err == nil && !opts.Force {
 		if opts.ErrOutput != nil {
 			_, _ = fmt.Fprintln(opts.ErrOutput, "Config already exists, skipping initialization:", target)
 		}
+		return false, nil
+	}
+
+	// T014: Marshal data
+	out, err := Marshal(Merge(opts.Data, opts.Overlays...), opts.Format)
+	if err != nil {
+		return false, err
+	}
+
+	// T013: Write file, replacing an existing one atomically after backing it up
+	if err := configfile.WriteFile(target, out); err != nil {
+		return false, err
+	}
+
+	// T016: Write success message
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintln(opts.ErrOutput, "Wrote config:", target)
+	}
+	if opts.Written != nil {
+		if err := opts.Written(); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// edit opens target in the editor of opts (T023-T025, Phase 3).
+func edit(target string, opts ConfigureOptions) error {
+	// T023: Call EditorLookup
+	ed, edArgs, err := opts.EditorLookup()
+	if err != nil {
+		// T024: Absorb EditorLookup errors
+		if opts.ErrOutput != nil {
+			_, _ = fmt.Fprintln(opts.ErrOutput, "No editor found:", err)
+		}
 		return nil
 	}
 
-	// Remove existing file if Force
-	if opts.Force {
-		_ = os.Remove(target)
+	// T026: Use proc package for editor launch
+	args := append(edArgs, target)
+	execCommand := opts.ExecCommand
+	if execCommand == nil {
+		execCommand = proc.ExecCommand
+	}
+	cmd := execCommand(ed, args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if opts.Input != nil {
+		cmd.Stdin = opts.Input
+	}
+	if opts.Output != nil {
+		cmd.Stdout = opts.Output
+	}
+	if opts.ErrOutput != nil {
+		cmd.Stderr = opts.ErrOutput
 	}
 
-	// T014: Marshal data
-	var out []byte
-	var err error
-	switch opts.Format {
-	case "yaml", "yml":
-		out, err = yaml.Marshal(opts.Data)
-	default:
-		out, err = json.MarshalIndent(opts.Data, "", "  ")
+	// T025: Determine wait based on EditorShouldWait
+	shouldWait := true
+	if opts.EditorShouldWait != nil {
+		shouldWait = opts.EditorShouldWait(ed, args)
 	}
+
+	// T026: Run editor via proc.Run
+	return proc.Run(cmd, shouldWait, opts.ErrOutput)
+}
+
+// preview prints to opts.Output what Configure would write to target: the
+// whole file for opts.DryRun, or for opts.Diff a unified diff from
+// opts.Current, a missing file comparing as empty. An existing file is
+// compared even when it would be kept for lack of opts.Force, so that it
+// can be reviewed before forcing. The diff shows the values of the keys
+// reported by opts.Secret as maskedValue on both sides; an existing file
+// holding such a value is then compared as re-serialized.
+func preview(target string, opts ConfigureOptions) error {
+	data := Merge(opts.Data, opts.Overlays...)
+	out, err := Marshal(data, opts.Format)
 	if err != nil {
 		return err
 	}
+	if !opts.Diff {
+		if _, err := opts.Output.Write(out); err != nil {
+			return err
+		}
+		if opts.ErrOutput != nil {
+			_, _ = fmt.Fprintln(opts.ErrOutput, "Would write config:", target)
+		}
+		return nil
+	}
 
-	// T013: Write file
-	if err := os.WriteFile(target, out, 0o644); err != nil {
+	current := opts.Current
+	if current == "" {
+		current = target
+	}
+	before, err := os.ReadFile(current)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
 		return err
 	}
+	if opts.Secret != nil {
+		if before, out, err = maskPreview(current, before, data, opts); err != nil {
+			return err
+		}
+	}
+	d := diff.Unified(current, target, before, out)
+	if d == "" {
+		if opts.ErrOutput != nil {
+			_, _ = fmt.Fprintln(opts.ErrOutput, "No changes:", target)
+		}
+		return nil
+	}
+	_, err = io.WriteString(opts.Output, d)
+	return err
+}
 
-	// T016: Write success message
-	if opts.ErrOutput != nil {
-		_, _ = fmt.Fprintln(opts.ErrOutput, "Wrote config:", target)
+// maskPreview returns before, the content of the file at current, and the
+// serialized data with the values of the keys reported by opts.Secret
+// masked. A masked value of data that differs from the one in before is
+// marked as changed, so that the diff still shows the change. before is
+// re-serialized only when it holds a masked value.
+func maskPreview(current string, before []byte, data map[string]interface{}, opts ConfigureOptions) ([]byte, []byte, error) {
+	format := FormatFromPath(current)
+	old, err := Unmarshal(before, format, keysOf(data))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", current, err)
 	}
+	if masked, ok := maskSecrets(old, opts.Secret, nil); ok {
+		if before, err = Marshal(masked, format); err != nil {
+			return nil, nil, err
+		}
+	}
+	masked, _ := maskSecrets(data, opts.Secret, old)
+	out, err := Marshal(masked, opts.Format)
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, out, nil
+}
 
-	// T023-T025: Editor launch (Phase 3)
-	if opts.Edit {
-		// T023: Call EditorLookup
-		ed, edArgs, err := opts.EditorLookup()
-		if err != nil {
-			// T024: Absorb EditorLookup errors
-			if opts.ErrOutput != nil {
-				_, _ = fmt.Fprintln(opts.ErrOutput, "No editor found:", err)
-			}
-			return nil
+// maskSecrets returns a copy of data with every non-empty value of a key
+// reported by secret replaced by maskedValue, and whether any was replaced.
+// A value differing from a non-empty value of the key in old is replaced by
+// changedMaskedValue instead.
+func maskSecrets(data map[string]interface{}, secret func(string) bool, old map[string]interface{}) (map[string]interface{}, bool) {
+	flat, oldFlat := map[string]interface{}{}, map[string]interface{}{}
+	flatten("", data, flat)
+	flatten("", old, oldFlat)
+	masked := Merge(data)
+	replaced := false
+	for key, v := range flat {
+		if isEmpty(v) || !secret(key) {
+			continue
+		}
+		mask := maskedValue
+		if prev, ok := oldFlat[key]; ok && !isEmpty(prev) && fmt.Sprint(prev) != fmt.Sprint(v) {
+			mask = changedMaskedValue
 		}
+		setNested(masked, strings.Split(key, "."), mask)
+		replaced = true
+	}
+	return masked, replaced
+}
 
-		// T026: Use proc package for editor launch
-		args := append(edArgs, target)
-		cmd := proc.ExecCommand(ed, args...)
-		cmd.Stdin = os.Stdin
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		// T025: Determine wait based on EditorShouldWait
-		shouldWait := true
-		if opts.EditorShouldWait != nil {
-			shouldWait = opts.EditorShouldWait(ed, args)
+// isEmpty reports whether v is nil or formats as an empty string.
+func isEmpty(v interface{}) bool {
+	return v == nil || fmt.Sprint(v) == ""
+}
+
+// keysOf returns the dotted keys of the values of data, nested maps
+// flattened.
+func keysOf(data map[string]interface{}) []string {
+	flat := map[string]interface{}{}
+	flatten("", data, flat)
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// Merge returns a copy of data with every overlay merged over it in order.
+// Nested maps are merged key by key; any other value of an overlay replaces
+// the value before it. Neither data nor the overlays are modified.
+func Merge(data map[string]interface{}, overlays ...map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	mergeInto(merged, data)
+	for _, overlay := range overlays {
+		mergeInto(merged, overlay)
+	}
+	return merged
+}
+
+// mergeInto merges src over dst, copying nested maps of src.
+func mergeInto(dst, src map[string]interface{}) {
+	for k, v := range src {
+		child, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		cur, ok := dst[k].(map[string]interface{})
+		if !ok {
+			cur = map[string]interface{}{}
+			dst[k] = cur
 		}
+		mergeInto(cur, child)
+	}
+}
+
+// Marshal serializes data as YAML when format is "yaml" or "yml", as TOML
+// when it is "toml", as KEY=VALUE lines when it is "dotenv" or "env", and as
+// indented JSON otherwise.
+func Marshal(data map[string]interface{}, format string) ([]byte, error) {
+	switch format {
+	case "yaml", "yml":
+		return yaml.Marshal(data)
+	case "toml":
+		return toml.Marshal(data)
+	case "dotenv", "env":
+		return MarshalDotenv(data), nil
+	default:
+		return json.MarshalIndent(data, "", "  ")
+	}
+}
 
-		// T026: Run editor via proc.Run
-		return proc.Run(cmd, shouldWait, opts.ErrOutput)
+// Unmarshal decodes b as TOML when format is "toml", as KEY=VALUE lines when
+// it is "dotenv", with names mapped back to the dotted keys in keys, and as
+// YAML (a superset of JSON) otherwise. Empty input yields an empty, non-nil
+// map.
+func Unmarshal(b []byte, format string, keys []string) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	var err error
+	switch format {
+	case "toml":
+		err = toml.Unmarshal(b, &data)
+	case "dotenv":
+		data, err = UnmarshalDotenv(b, keys)
+	default:
+		err = yaml.Unmarshal(b, &data)
+	}
+	if err != nil {
+		return nil, err
 	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return data, nil
+}
 
-	return nil
+// WriteFile marshals data according to format and replaces the file at target
+// through configfile.WriteFile: atomically, after backing up the current
+// version, and with private permissions for new files and directories.
+func WriteFile(target string, data map[string]interface{}, format string) error {
+	out, err := Marshal(data, format)
+	if err != nil {
+		return err
+	}
+	return configfile.WriteFile(target, out)
 }
 
 // ConfigureFunc is a variable indirection for testing.
@@ -336,6 +336,32 @@ func TestConfigure_Edit_EditorFound(t *testing.T) {
 	}
 }
 
+// TestConfigure_Edit_UsesStreams verifies that the editor is attached to the
+// streams of the options rather than the process' stdio.
+func TestConfigure_Edit_UsesStreams(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "config.yaml")
+	var out, errOut bytes.Buffer
+	opts := configure.ConfigureOptions{
+		Edit:         true,
+		Data:         map[string]interface{}{"key": "value"},
+		Format:       "yaml",
+		Input:        strings.NewReader("typed"),
+		Output:       &out,
+		ErrOutput:    &errOut,
+		EditorLookup: func() (string, []string, error) { return "editor", nil, nil },
+		ExecCommand: func(string, ...string) *exec.Cmd {
+			return exec.Command("sh", "-c", "cat; echo oops >&2")
+		},
+	}
+
+	if err := configure.Configure(target, opts); err != nil {
+		t.Fatalf("Configure failed: %v", err)
+	}
+	if out.String() != "typed" || !strings.Contains(errOut.String(), "oops") {
+		t.Errorf("editor output = %q, error output = %q", out.String(), errOut.String())
+	}
+}
+
 // T021: Editor not found test
 func TestConfigure_Edit_EditorNotFound(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -429,3 +455,160 @@ func TestConfigure_Edit_NoWait(t *testing.T) {
 		t.Errorf("expected EditorShouldWait to return false, got: %v", shouldWaitResult)
 	}
 }
+
+func TestWriteFile_Unmarshal_RoundTrip(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "nested", "config.yaml")
+	data := map[string]interface{}{
+		"common": map[string]interface{}{"var2": 5},
+	}
+
+	if err := configure.WriteFile(target, data, "yaml"); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := configure.Unmarshal(b, configure.FormatFromPath(target), nil)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	common, ok := got["common"].(map[string]interface{})
+	if !ok || common["var2"] != 5 {
+		t.Errorf("round-trip mismatch: %v", got)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	data := map[string]interface{}{"a": 1, "common": map[string]interface{}{"x": "1", "y": "2"}}
+	overlays := []map[string]interface{}{
+		{"common": map[string]interface{}{"x": "file"}, "b": true},
+		{"common": map[string]interface{}{"x": "set"}},
+	}
+
+	got := configure.Merge(data, overlays...)
+	common := got["common"].(map[string]interface{})
+	if got["a"] != 1 || got["b"] != true || common["x"] != "set" || common["y"] != "2" {
+		t.Errorf("unexpected merge result: %v", got)
+	}
+	if data["common"].(map[string]interface{})["x"] != "1" || len(data) != 2 {
+		t.Errorf("expected data to be unchanged, got %v", data)
+	}
+}
+
+func TestConfigure_Overlays(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "config.yaml")
+	opts := configure.ConfigureOptions{
+		Data:      map[string]interface{}{"key": "value", "other": "kept"},
+		Overlays:  []map[string]interface{}{{"key": "overlay"}},
+		Format:    "yaml",
+		ErrOutput: &bytes.Buffer{},
+	}
+	if err := configure.Configure(target, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	content, err := os.ReadFile(target)
+	if err != nil || string(content) != "key: overlay\nother: kept\n" {
+		t.Errorf("content = %q, %v", content, err)
+	}
+}
+
+func TestConfigure_DryRun(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "sub", "config.yaml")
+	var out, errOut bytes.Buffer
+	opts := configure.ConfigureOptions{
+		DryRun:    true,
+		Data:      map[string]interface{}{"key": "value"},
+		Format:    "yaml",
+		Output:    &out,
+		ErrOutput: &errOut,
+	}
+	if err := configure.Configure(target, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "key: value\n" || !strings.Contains(errOut.String(), "Would write config:") {
+		t.Errorf("output = %q, messages = %q", out.String(), errOut.String())
+	}
+	if _, err := os.Stat(filepath.Dir(target)); !os.IsNotExist(err) {
+		t.Errorf("expected nothing to be created, got %v", err)
+	}
+}
+
+func TestConfigure_Diff(t *testing.T) {
+	dir := t.TempDir()
+	current := filepath.Join(dir, "config.json")
+	target := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(current, []byte(`{"key": "old"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out, errOut bytes.Buffer
+	opts := configure.ConfigureOptions{
+		Diff:      true,
+		Current:   current,
+		Data:      map[string]interface{}{"key": "new"},
+		Format:    "yaml",
+		Output:    &out,
+		ErrOutput: &errOut,
+	}
+	if err := configure.Configure(target, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "--- " + current + "\n+++ " + target + "\n@@ -1 +1 @@\n-{\"key\": \"old\"}\n+key: new\n"
+	if out.String() != want {
+		t.Errorf("diff = %q, want %q", out.String(), want)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to be written, got %v", target, err)
+	}
+
+	// the target itself is compared by default
+	out.Reset()
+	opts.Current = ""
+	opts.Format = "json"
+	if err := configure.Configure(current, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "+  \"key\": \"new\"") {
+		t.Errorf("unexpected diff: %q", out.String())
+	}
+
+	out.Reset()
+	opts.Data = map[string]interface{}{"key": "old"}
+	if err := os.WriteFile(current, []byte("{\n  \"key\": \"old\"\n}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := configure.Configure(current, opts); err != nil || out.Len() != 0 || !strings.Contains(errOut.String(), "No changes:") {
+		t.Errorf("expected no diff, got %q, %q, %v", out.String(), errOut.String(), err)
+	}
+}
+
+func TestConfigure_DiffMasksSecrets(t *testing.T) {
+	current := filepath.Join(t.TempDir(), "config.env")
+	if err := os.WriteFile(current, []byte("CLIENT_SECRET=old\nSECTION_TOKEN=same\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	opts := configure.ConfigureOptions{
+		Diff:    true,
+		Current: current,
+		Data: map[string]interface{}{
+			"client-secret": "new",
+			"section":       map[string]interface{}{"token": "same"},
+			"empty":         "",
+		},
+		Format: "dotenv",
+		Secret: func(key string) bool { return key != "section" },
+		Output: &out,
+	}
+	if err := configure.Configure(current, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "--- " + current + "\n+++ " + current + "\n@@ -1,2 +1,3 @@\n" +
+		"-CLIENT_SECRET=********\n+CLIENT_SECRET=\"******** (changed)\"\n+EMPTY=\"\"\n SECTION_TOKEN=********\n"
+	if out.String() != want {
+		t.Errorf("diff = %q, want %q", out.String(), want)
+	}
+}
@@ -0,0 +1,202 @@
+package configure
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Formats lists the supported configuration file formats. The first one is
+// the default used for new files.
+var Formats = []string{"yaml", "json", "toml", "dotenv"}
+
+// extensions maps file extensions (without the dot) to formats.
+var extensions = map[string]string{
+	"yaml":   "yaml",
+	"yml":    "yaml",
+	"json":   "json",
+	"toml":   "toml",
+	"env":    "dotenv",
+	"dotenv": "dotenv",
+}
+
+// Extensions returns the recognized file extensions in lookup order:
+// YAML first, then JSON, TOML and dotenv.
+func Extensions() []string {
+	return []string{"yaml", "yml", "json", "toml", "env", "dotenv"}
+}
+
+// FormatFromPath returns the format of the file at path based on its
+// extension, or "" if the extension is not recognized.
+func FormatFromPath(path string) string {
+	return extensions[strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")]
+}
+
+// Extension returns the file extension (without the dot) used for new files
+// of the given format.
+func Extension(format string) (string, error) {
+	switch format {
+	case "yaml", "yml":
+		return "yaml", nil
+	case "json", "toml":
+		return format, nil
+	case "dotenv", "env":
+		return "env", nil
+	}
+	return "", fmt.Errorf("unsupported format: %s (want one of %s)", format, strings.Join(Formats, ", "))
+}
+
+// MarshalDotenv renders data as KEY=VALUE lines sorted by key. Dotted keys
+// are flattened and turned into upper-case names with "." and "-" replaced
+// by "_" (e.g. common.var2 becomes COMMON_VAR2).
+func MarshalDotenv(data map[string]interface{}) []byte {
+	flat := map[string]interface{}{}
+	flatten("", data, flat)
+
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		fmt.Fprintf(&buf, "%s=%s\n", DotenvName(k), quoteDotenv(flat[k]))
+	}
+	return buf.Bytes()
+}
+
+// UnmarshalDotenv parses KEY=VALUE lines into a nested map. Names are mapped
+// back to the dotted keys in keys; unknown names are kept as lower-case keys
+// so that strict validation can report them. Values are kept as strings.
+func UnmarshalDotenv(b []byte, keys []string) (map[string]interface{}, error) {
+	names := map[string]string{}
+	for _, k := range keys {
+		names[DotenvName(k)] = k
+	}
+
+	data := map[string]interface{}{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", n)
+		}
+		value, err := unquoteDotenv(strings.TrimSpace(raw))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		name = strings.TrimSpace(name)
+		key, known := names[strings.ToUpper(name)]
+		if !known {
+			key = strings.ToLower(name)
+		}
+		setNested(data, strings.Split(key, "."), value)
+	}
+	return data, scanner.Err()
+}
+
+// DotenvName returns the dotenv variable name of a dotted key.
+func DotenvName(key string) string {
+	return strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
+}
+
+// DotenvCodec adapts MarshalDotenv/UnmarshalDotenv to the encoder and decoder
+// interfaces of viper's codec registry.
+type DotenvCodec struct {
+	Keys []string // Keys are the known dotted keys used to map names back
+}
+
+// Encode implements viper.Encoder.
+func (c DotenvCodec) Encode(v map[string]interface{}) ([]byte, error) {
+	return MarshalDotenv(v), nil
+}
+
+// Decode implements viper.Decoder.
+func (c DotenvCodec) Decode(b []byte, v map[string]interface{}) error {
+	data, err := UnmarshalDotenv(b, c.Keys)
+	if err != nil {
+		return err
+	}
+	for k, val := range data {
+		v[k] = val
+	}
+	return nil
+}
+
+// flatten copies the leaves of data into flat keyed by dotted path.
+func flatten(prefix string, data map[string]interface{}, flat map[string]interface{}) {
+	for k, v := range data {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		if child, ok := v.(map[string]interface{}); ok {
+			flatten(key, child, flat)
+			continue
+		}
+		flat[key] = v
+	}
+}
+
+// setNested stores value under the path parts, creating sections as needed.
+func setNested(data map[string]interface{}, parts []string, value interface{}) {
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := data[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			data[part] = next
+		}
+		data = next
+	}
+	data[parts[len(parts)-1]] = value
+}
+
+// quoteDotenv renders a value, double-quoting it when it is not a plain word.
+func quoteDotenv(v interface{}) string {
+	var s string
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, len(val))
+		for i, item := range val {
+			parts[i] = fmt.Sprint(item)
+		}
+		s = strings.Join(parts, ",")
+	case []string:
+		s = strings.Join(val, ",")
+	default:
+		s = fmt.Sprint(val)
+	}
+	if s == "" || strings.ContainsAny(s, " \t\r\n\"'#$\\=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// unquoteDotenv strips double or single quotes from a raw value.
+func unquoteDotenv(raw string) (string, error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		return strconv.Unquote(raw)
+	case strings.HasPrefix(raw, "'"):
+		if len(raw) < 2 || !strings.HasSuffix(raw, "'") {
+			return "", fmt.Errorf("unterminated quote")
+		}
+		return raw[1 : len(raw)-1], nil
+	}
+	if i := strings.Index(raw, " #"); i >= 0 {
+		raw = strings.TrimSpace(raw[:i])
+	}
+	return raw, nil
+}
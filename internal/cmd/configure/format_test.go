@@ -0,0 +1,92 @@
+package configure_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/configure"
+)
+
+func TestFormatFromPath(t *testing.T) {
+	tests := map[string]string{
+		"default.yaml": "yaml",
+		"default.YML":  "yaml",
+		"prod.json":    "json",
+		"prod.toml":    "toml",
+		"prod.env":     "dotenv",
+		".mycli":       "",
+		"prod.ini":     "",
+	}
+	for path, want := range tests {
+		if got := configure.FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q; want %q", path, got, want)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	for format, want := range map[string]string{"yaml": "yaml", "yml": "yaml", "json": "json", "toml": "toml", "dotenv": "env"} {
+		got, err := configure.Extension(format)
+		if err != nil || got != want {
+			t.Errorf("Extension(%q) = %q, %v; want %q", format, got, err, want)
+		}
+	}
+	if _, err := configure.Extension("ini"); err == nil {
+		t.Error("expected error for unsupported format")
+	}
+}
+
+func TestMarshal_TOML(t *testing.T) {
+	out, err := configure.Marshal(map[string]interface{}{
+		"client-id": "abc",
+		"common":    map[string]interface{}{"var2": 5},
+	}, "toml")
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	s := string(out)
+	if !strings.Contains(s, "client-id = 'abc'") || !strings.Contains(s, "[common]\nvar2 = 5") {
+		t.Errorf("unexpected TOML:\n%s", s)
+	}
+}
+
+func TestDotenv_RoundTrip(t *testing.T) {
+	data := map[string]interface{}{
+		"client-id": "a b",
+		"common":    map[string]interface{}{"var1": "", "var2": 123},
+		"hoge":      map[string]interface{}{"foo": map[string]interface{}{"bar": "x#y"}},
+	}
+	out := configure.MarshalDotenv(data)
+	want := "CLIENT_ID=\"a b\"\nCOMMON_VAR1=\"\"\nCOMMON_VAR2=123\nHOGE_FOO_BAR=\"x#y\"\n"
+	if string(out) != want {
+		t.Fatalf("MarshalDotenv =\n%s\nwant\n%s", out, want)
+	}
+
+	keys := []string{"client-id", "common.var1", "common.var2", "hoge.foo.bar"}
+	got, err := configure.UnmarshalDotenv(append(out, []byte("# comment\nexport EXTRA='v' \n")...), keys)
+	if err != nil {
+		t.Fatalf("UnmarshalDotenv failed: %v", err)
+	}
+	if got["client-id"] != "a b" {
+		t.Errorf("client-id = %v", got["client-id"])
+	}
+	common, _ := got["common"].(map[string]interface{})
+	if common["var2"] != "123" || common["var1"] != "" {
+		t.Errorf("common = %v", common)
+	}
+	foo, _ := got["hoge"].(map[string]interface{})["foo"].(map[string]interface{})
+	if foo["bar"] != "x#y" {
+		t.Errorf("hoge.foo.bar = %v", foo["bar"])
+	}
+	if got["extra"] != "v" {
+		t.Errorf("unknown names should be kept lower-cased, got %v", got)
+	}
+}
+
+func TestUnmarshalDotenv_Errors(t *testing.T) {
+	for _, in := range []string{"NO_EQUALS\n", "A=\"unterminated\n", "A='x\n"} {
+		if _, err := configure.UnmarshalDotenv([]byte(in), nil); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
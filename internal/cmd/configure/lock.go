@@ -0,0 +1,135 @@
+package configure
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rising3/go-cli/internal/fileperm"
+)
+
+// LockTimeout bounds how long Lock waits for the holder of a profile's lock
+// to release it.
+var LockTimeout = 10 * time.Second
+
+// lockRetryInterval is the delay between two attempts to take a busy lock.
+const lockRetryInterval = 50 * time.Millisecond
+
+// LockedError is returned by Lock when the lock of a profile is held for
+// longer than LockTimeout.
+type LockedError struct {
+	Profile string // Profile is the name of the locked profile
+	Path    string // Path is the lock file
+	PID     int    // PID is the process holding the lock, 0 if unknown
+}
+
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return fmt.Sprintf("profile %s is locked by another process (%s)", e.Profile, e.Path)
+	}
+	return fmt.Sprintf("profile %s is locked by pid %d (%s)", e.Profile, e.PID, e.Path)
+}
+
+// LockPath returns the path of the lock file of the config file at target.
+// Files of the same profile in different formats share one lock, e.g.
+// dev.yaml and dev.env are both locked with .dev.lock.
+func LockPath(target string) string {
+	base := filepath.Base(target)
+	return filepath.Join(filepath.Dir(target), "."+strings.TrimSuffix(base, filepath.Ext(base))+".lock")
+}
+
+// Lock takes the advisory lock of the config file at target, waiting up to
+// LockTimeout for its holder to release it, and returns the function
+// releasing it. The holder may be another process or another caller in this
+// one: Lock is not reentrant, so work that must happen under a lock taken by
+// a callee is passed to it instead (see ConfigureOptions.Check). The lock file
+// records the pid of its holder so that the error names it; a lock left
+// behind by a process that is no longer running is taken over.
+func Lock(target string) (func(), error) {
+	path := LockPath(target)
+	if err := fileperm.MkdirAll(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(LockTimeout)
+	for {
+		f, err := tryLock(path)
+		if err == nil {
+			var once sync.Once
+			return func() { once.Do(func() { releaseLock(path, f) }) }, nil
+		}
+		if !errors.Is(err, errLockBusy) {
+			return nil, fmt.Errorf("lock %s: %w", path, err)
+		}
+		if time.Now().After(deadline) {
+			profile := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(path), "."), ".lock")
+			return nil, &LockedError{Profile: profile, Path: path, PID: readLockPID(path)}
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// WithLock runs fn while holding the lock of the config file at target (see
+// Lock).
+func WithLock(target string, fn func() error) error {
+	unlock, err := Lock(target)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	return fn()
+}
+
+// WithLocks runs fn while holding the locks of the config files at targets.
+// The locks are taken in the order of their lock files, so that callers
+// locking the same profiles in another order cannot deadlock, and a lock
+// shared by several targets is taken once.
+func WithLocks(targets []string, fn func() error) error {
+	byPath := map[string]string{}
+	for _, target := range targets {
+		byPath[LockPath(target)] = target
+	}
+	paths := make([]string, 0, len(byPath))
+	for path := range byPath {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		unlock, err := Lock(byPath[path])
+		if err != nil {
+			return err
+		}
+		defer unlock()
+	}
+	return fn()
+}
+
+// errLockBusy is returned by tryLock when another process holds the lock.
+var errLockBusy = errors.New("lock is busy")
+
+// writeLockPID records the current process as the holder of the lock file f.
+func writeLockPID(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+	_, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	return err
+}
+
+// readLockPID returns the pid recorded in the lock file at path, or 0.
+func readLockPID(path string) int {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(b)))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
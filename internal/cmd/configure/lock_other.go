@@ -0,0 +1,47 @@
+//go:build !unix
+
+package configure
+
+import (
+	"errors"
+	"os"
+
+	"github.com/rising3/go-cli/internal/fileperm"
+)
+
+// tryLock creates the lock file at path exclusively. An existing lock file
+// whose recorded process is no longer running is stale and replaced.
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_EXCL, fileperm.FileMode)
+	if errors.Is(err, os.ErrExist) {
+		if pid := readLockPID(path); pid != 0 && !processRunning(pid) {
+			_ = os.Remove(path)
+		}
+		return nil, errLockBusy
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := writeLockPID(f); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return nil, err
+	}
+	return f, nil
+}
+
+// releaseLock closes f and removes the lock file at path.
+func releaseLock(path string, f *os.File) {
+	_ = f.Close()
+	_ = os.Remove(path)
+}
+
+// processRunning reports whether a process with the given pid exists.
+func processRunning(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	_ = p.Release()
+	return true
+}
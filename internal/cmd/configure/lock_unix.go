@@ -0,0 +1,40 @@
+//go:build unix
+
+package configure
+
+import (
+	"errors"
+	"os"
+	"syscall"
+
+	"github.com/rising3/go-cli/internal/fileperm"
+)
+
+// tryLock takes an exclusive flock on the lock file at path without
+// blocking. The kernel releases it when its holder exits, so a lock file left
+// behind by a process that is no longer running never blocks.
+func tryLock(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, fileperm.FileMode)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		_ = f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, errLockBusy
+		}
+		return nil, err
+	}
+	if err := writeLockPID(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// releaseLock releases the flock on f. The lock file is kept, since removing
+// it could let two processes lock different files of the same path.
+func releaseLock(_ string, f *os.File) {
+	_ = f.Truncate(0)
+	_ = f.Close()
+}
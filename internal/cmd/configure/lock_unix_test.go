@@ -0,0 +1,178 @@
+//go:build unix
+
+package configure_test
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/rising3/go-cli/internal/cmd/configure"
+)
+
+// holdLock takes the flock of target's lock file the way another process
+// would, recording pid as its holder.
+func holdLock(t *testing.T, target string, pid int) {
+	t.Helper()
+	f, err := os.OpenFile(configure.LockPath(target), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = f.Close() })
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(strconv.Itoa(pid) + "\n"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func shortLockTimeout(t *testing.T) {
+	t.Helper()
+	orig := configure.LockTimeout
+	configure.LockTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { configure.LockTimeout = orig })
+}
+
+func TestLock_Busy(t *testing.T) {
+	shortLockTimeout(t)
+	target := filepath.Join(t.TempDir(), "dev.yaml")
+	holdLock(t, target, 4242)
+
+	start := time.Now()
+	_, err := configure.Lock(filepath.Join(filepath.Dir(target), "dev.env"))
+	var locked *configure.LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("expected a *LockedError, got %v", err)
+	}
+	if locked.PID != 4242 || !strings.HasPrefix(err.Error(), "profile dev is locked by pid 4242") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if time.Since(start) < configure.LockTimeout {
+		t.Error("expected Lock to wait for LockTimeout")
+	}
+
+	err = configure.Configure(target, configure.ConfigureOptions{Data: map[string]interface{}{}, Format: "yaml"})
+	if !errors.As(err, &locked) {
+		t.Errorf("expected Configure to fail on a locked profile, got %v", err)
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Error("expected no file to be written")
+	}
+}
+
+func TestLock_NotReentrantAndStale(t *testing.T) {
+	shortLockTimeout(t)
+	target := filepath.Join(t.TempDir(), "dev.yaml")
+	// a lock file left behind by a process that is no longer running
+	if err := os.WriteFile(configure.LockPath(target), []byte("999999\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	unlock, err := configure.Lock(target)
+	if err != nil {
+		t.Fatalf("expected the stale lock to be taken over, got %v", err)
+	}
+	b, _ := os.ReadFile(configure.LockPath(target))
+	if strings.TrimSpace(string(b)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("lock file = %q; want the current pid", b)
+	}
+
+	var locked *configure.LockedError
+	if _, err := configure.Lock(target); !errors.As(err, &locked) || locked.PID != os.Getpid() {
+		t.Errorf("expected a second Lock to wait for the first one, got %v", err)
+	}
+	unlock()
+	unlock()
+
+	// released: another process can take it
+	f, err := os.OpenFile(configure.LockPath(target), os.O_RDWR, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Errorf("expected the lock to be released, got %v", err)
+	}
+}
+
+// TestConfigure_LockedSection verifies that Check and Written run under the
+// lock taken by Configure, and that the editor runs after it is released.
+func TestConfigure_LockedSection(t *testing.T) {
+	shortLockTimeout(t)
+	target := filepath.Join(t.TempDir(), "dev.yaml")
+	locked := func() bool {
+		unlock, err := configure.Lock(target)
+		if err == nil {
+			unlock()
+		}
+		return err != nil
+	}
+
+	var checked, written, edited bool
+	opts := configure.ConfigureOptions{
+		Edit:         true,
+		Data:         map[string]interface{}{"a": 1},
+		Format:       "yaml",
+		Check:        func() error { checked = locked(); return nil },
+		Written:      func() error { written = locked(); return nil },
+		EditorLookup: func() (string, []string, error) { return "true", nil, nil },
+		ExecCommand: func(name string, args ...string) *exec.Cmd {
+			edited = !locked()
+			return exec.Command("true")
+		},
+	}
+	if err := configure.Configure(target, opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !checked || !written || !edited {
+		t.Errorf("checked under lock %v, written under lock %v, edited without lock %v", checked, written, edited)
+	}
+
+	opts.Force = true
+	opts.Data = map[string]interface{}{"a": 2}
+	opts.Check = func() error { return errors.New("changed") }
+	if err := configure.Configure(target, opts); err == nil || err.Error() != "changed" {
+		t.Errorf("expected the Check error, got %v", err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "a: 1\n" {
+		t.Errorf("expected nothing to be written after a failed Check, got %q", b)
+	}
+}
+
+// TestLock_Goroutines verifies that the lock excludes other goroutines of the
+// same process.
+func TestLock_Goroutines(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "dev.yaml")
+	unlock, err := configure.Lock(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var released atomic.Bool
+	done := make(chan error)
+	go func() {
+		unlock, err := configure.Lock(target)
+		if err == nil {
+			if !released.Load() {
+				err = errors.New("took the lock while the first goroutine held it")
+			}
+			unlock()
+		}
+		done <- err
+	}()
+
+	time.Sleep(200 * time.Millisecond)
+	released.Store(true)
+	unlock()
+	if err := <-done; err != nil {
+		t.Errorf("second goroutine: %v", err)
+	}
+}
@@ -0,0 +1,78 @@
+package configure
+
+import (
+	"fmt"
+
+	"github.com/rising3/go-cli/internal/prompt"
+	"github.com/rising3/go-cli/internal/stdio"
+)
+
+// maskedValue is shown instead of the current value of a secret question.
+const maskedValue = "********"
+
+// changedMaskedValue is shown by a Diff preview instead of a changed secret.
+const changedMaskedValue = maskedValue + " (changed)"
+
+// Question is a configuration value asked by Wizard.
+type Question struct {
+	Key         string                                // Key is the dotted configuration key
+	Description string                                // Description is shown next to the key when not empty
+	Current     string                                // Current is the value kept when the answer is empty
+	Secret      bool                                  // Secret masks the current value and the typed answer
+	Parse       func(raw string) (interface{}, error) // Parse converts and validates an answer; nil keeps it as a string
+}
+
+// Wizard asks each question in turn on streams.Out and reads the answers
+// from streams.In, one line each, as:
+//
+//	key (description) [current]:
+//
+// An empty answer keeps the current value and is left out of the returned
+// map; any other answer is converted with Parse, and asked again after the
+// error is printed to streams.Err when it is invalid. The answers are
+// returned by key once every question is answered, so that nothing is
+// written when the input ends early.
+func Wizard(questions []Question, streams stdio.Streams) (map[string]interface{}, error) {
+	p := prompt.New(streams)
+	answers := map[string]interface{}{}
+	for _, q := range questions {
+		for {
+			raw, err := ask(p, q)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", q.Key, err)
+			}
+			if raw == "" {
+				break
+			}
+			if q.Parse == nil {
+				answers[q.Key] = raw
+				break
+			}
+			v, err := q.Parse(raw)
+			if err != nil {
+				_, _ = fmt.Fprintln(streams.Err, "Invalid value:", err)
+				continue
+			}
+			answers[q.Key] = v
+			break
+		}
+	}
+	return answers, nil
+}
+
+// ask prints the prompt of q and reads the answer.
+func ask(p *prompt.Prompter, q Question) (string, error) {
+	question := q.Key
+	if q.Description != "" {
+		question += " (" + q.Description + ")"
+	}
+	current := q.Current
+	if q.Secret && current != "" {
+		current = maskedValue
+	}
+	question += " [" + current + "]: "
+	if q.Secret {
+		return p.AskSecret(question)
+	}
+	return p.Ask(question)
+}
@@ -0,0 +1,48 @@
+package configure_test
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/prompt"
+	"github.com/rising3/go-cli/internal/stdio"
+)
+
+func wizardQuestions() []configure.Question {
+	return []configure.Question{
+		{Key: "name", Description: "user name", Current: "alice"},
+		{Key: "token", Current: "s3cr3t", Secret: true},
+		{Key: "count", Current: "1", Parse: func(raw string) (interface{}, error) { return strconv.Atoi(raw) }},
+	}
+}
+
+func TestWizard(t *testing.T) {
+	var out, errOut bytes.Buffer
+	streams := stdio.Streams{In: strings.NewReader("\nnew\nmany\n2\n"), Out: &out, Err: &errOut}
+
+	answers, err := configure.Wizard(wizardQuestions(), streams)
+	if err != nil {
+		t.Fatalf("Wizard failed: %v", err)
+	}
+	if len(answers) != 2 || answers["token"] != "new" || answers["count"] != 2 {
+		t.Errorf("unexpected answers: %v", answers)
+	}
+	want := "name (user name) [alice]: token [********]: count [1]: count [1]: "
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+	if strings.Contains(out.String(), "s3cr3t") || !strings.Contains(errOut.String(), "Invalid value:") {
+		t.Errorf("unexpected error output: %q", errOut.String())
+	}
+}
+
+func TestWizard_EndOfInput(t *testing.T) {
+	streams := stdio.Streams{In: strings.NewReader("bob\n"), Out: &bytes.Buffer{}, Err: &bytes.Buffer{}}
+	if _, err := configure.Wizard(wizardQuestions(), streams); !errors.Is(err, prompt.ErrNoInput) {
+		t.Errorf("expected ErrNoInput, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+// Package migrate implements the business logic of the `config migrate`
+// command, which upgrades config files to the current schema version by
+// applying a registry of migration steps.
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/rising3/go-cli/internal/cmd/config"
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/configfile"
+	"github.com/rising3/go-cli/internal/diff"
+)
+
+// BackupSuffix is appended to the path of a migrated file to name its backup.
+const BackupSuffix = ".bak"
+
+// Migration is a step upgrading config data from schema version From to
+// From+1.
+type Migration struct {
+	From        int                                     // From is the schema version the step upgrades from
+	Description string                                  // Description summarizes the change for users
+	Apply       func(data map[string]interface{}) error // Apply modifies data in place; nil only records the version
+}
+
+// Options represents the configuration for the config migrate command.
+type Options struct {
+	Key        string      // Key is the configuration key holding the schema version
+	Migrations []Migration // Migrations is the registry, ordered by From starting at 0
+	DryRun     bool        // DryRun prints the diff without writing any file
+	Output     io.Writer   // Output is the standard output stream for diffs
+	ErrOutput  io.Writer   // ErrOutput is the error output stream for messages
+}
+
+// Latest returns the schema version reached after every migration.
+func Latest(migrations []Migration) int {
+	return len(migrations)
+}
+
+// Version returns the schema version recorded under key in data. Data
+// without the key predates versioning and is version 0.
+func Version(data map[string]interface{}, key string) (int, error) {
+	v, ok := config.GetValue(data, key)
+	if !ok {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(fmt.Sprint(v))
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %v", key, v)
+	}
+	return n, nil
+}
+
+// Pending returns the migrations to apply to data, or an error if data was
+// written by a newer version.
+func Pending(data map[string]interface{}, opts Options) ([]Migration, error) {
+	version, err := Version(data, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+	if latest := Latest(opts.Migrations); version > latest {
+		return nil, fmt.Errorf("%s %d is newer than the supported version %d", opts.Key, version, latest)
+	}
+	return opts.Migrations[version:], nil
+}
+
+// Apply runs the pending migrations on data in place and records the latest
+// schema version under opts.Key. It returns the applied migrations.
+func Apply(data map[string]interface{}, opts Options) ([]Migration, error) {
+	pending, err := Pending(data, opts)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range pending {
+		if m.Apply == nil {
+			continue
+		}
+		if err := m.Apply(data); err != nil {
+			return nil, fmt.Errorf("migration from %s %d (%s): %w", opts.Key, m.From, m.Description, err)
+		}
+	}
+	config.SetValue(data, opts.Key, Latest(opts.Migrations))
+	return pending, nil
+}
+
+// Changes returns the migrations of pending that modify data, leaving out
+// the steps that only record the schema version.
+func Changes(pending []Migration) []Migration {
+	var changes []Migration
+	for _, m := range pending {
+		if m.Apply != nil {
+			changes = append(changes, m)
+		}
+	}
+	return changes
+}
+
+// Stamp records the latest schema version under opts.Key in data when none of
+// its pending migrations modify it, so that writers can mark the files they
+// write as current. Data needing real changes, or with an invalid or newer
+// version, is left alone. It reports whether data was stamped.
+func Stamp(data map[string]interface{}, opts Options) bool {
+	pending, err := Pending(data, opts)
+	if err != nil || len(pending) == 0 || len(Changes(pending)) > 0 {
+		return false
+	}
+	config.SetValue(data, opts.Key, Latest(opts.Migrations))
+	return true
+}
+
+// File migrates the config file at path, whose settings have been read into
+// data, and prints a unified diff of the change to opts.Output. Unless
+// opts.DryRun is set, the original file is kept as path+BackupSuffix and
+// path is rewritten in the format detected from its extension.
+func File(path string, data map[string]interface{}, opts Options) error {
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	from, err := Version(data, opts.Key)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	applied, err := Apply(data, opts)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if len(applied) == 0 {
+		if opts.ErrOutput != nil {
+			_, _ = fmt.Fprintf(opts.ErrOutput, "Up to date: %s (%s %d)\n", path, opts.Key, from)
+		}
+		return nil
+	}
+
+	after, err := configure.Marshal(data, configure.FormatFromPath(path))
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(opts.Output, diff.Unified(path, path, before, after)); err != nil {
+		return err
+	}
+	if opts.DryRun {
+		if opts.ErrOutput != nil {
+			_, _ = fmt.Fprintf(opts.ErrOutput, "Would migrate %s from %s %d to %d\n", path, opts.Key, from, Latest(opts.Migrations))
+		}
+		return nil
+	}
+
+	backup := path + BackupSuffix
+	if err := configfile.WriteAtomic(backup, before); err != nil {
+		return err
+	}
+	if err := configfile.WriteFile(path, after); err != nil {
+		return err
+	}
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintf(opts.ErrOutput, "Migrated %s from %s %d to %d (backup: %s)\n", path, opts.Key, from, Latest(opts.Migrations), backup)
+	}
+	return nil
+}
+
+// RenameKey moves the value of the dotted key oldKey to newKey, pruning
+// sections left empty. Missing keys are ignored. It is a building block for
+// migrations that rename or move fields.
+func RenameKey(data map[string]interface{}, oldKey, newKey string) {
+	v, ok := config.GetValue(data, oldKey)
+	if !ok {
+		return
+	}
+	config.UnsetValue(data, oldKey)
+	config.SetValue(data, newKey, v)
+}
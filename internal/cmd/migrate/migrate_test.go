@@ -0,0 +1,142 @@
+package migrate_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/migrate"
+)
+
+func newOptions() (migrate.Options, *bytes.Buffer) {
+	var out bytes.Buffer
+	return migrate.Options{
+		Key: "schema-version",
+		Migrations: []migrate.Migration{
+			{From: 0, Description: "record version", Apply: func(map[string]interface{}) error { return nil }},
+			{From: 1, Description: "rename hoge.fuga", Apply: func(data map[string]interface{}) error {
+				migrate.RenameKey(data, "hoge.fuga", "common.fuga")
+				return nil
+			}},
+		},
+		Output:    &out,
+		ErrOutput: &bytes.Buffer{},
+	}, &out
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		data    map[string]interface{}
+		want    int
+		wantErr bool
+	}{
+		{map[string]interface{}{}, 0, false},
+		{map[string]interface{}{"schema-version": 2}, 2, false},
+		{map[string]interface{}{"schema-version": "1"}, 1, false},
+		{map[string]interface{}{"schema-version": "x"}, 0, true},
+		{map[string]interface{}{"schema-version": -1}, 0, true},
+	}
+	for _, tt := range tests {
+		got, err := migrate.Version(tt.data, "schema-version")
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("Version(%v) = %d, %v; want %d (error %v)", tt.data, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	opts, _ := newOptions()
+	data := map[string]interface{}{
+		"schema-version": 1,
+		"hoge":           map[string]interface{}{"fuga": "x"},
+	}
+	applied, err := migrate.Apply(data, opts)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+	if len(applied) != 1 || applied[0].From != 1 {
+		t.Errorf("applied = %v", applied)
+	}
+	if _, ok := data["hoge"]; ok {
+		t.Errorf("expected empty hoge section to be pruned: %v", data)
+	}
+	if data["common"].(map[string]interface{})["fuga"] != "x" || data["schema-version"] != 2 {
+		t.Errorf("unexpected data: %v", data)
+	}
+
+	if _, err := migrate.Apply(map[string]interface{}{"schema-version": 3}, opts); err == nil {
+		t.Error("expected error for a newer schema version")
+	}
+
+	opts.Migrations[0].Apply = func(map[string]interface{}) error { return errors.New("boom") }
+	if _, err := migrate.Apply(map[string]interface{}{}, opts); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected migration error, got: %v", err)
+	}
+}
+
+func TestStamp(t *testing.T) {
+	opts, _ := newOptions()
+	opts.Migrations[0].Apply = nil
+
+	data := map[string]interface{}{"schema-version": 1}
+	if migrate.Stamp(data, opts) {
+		t.Error("expected data needing a rename to be left alone")
+	}
+	if len(migrate.Changes(opts.Migrations)) != 1 {
+		t.Errorf("Changes() = %v", migrate.Changes(opts.Migrations))
+	}
+
+	opts.Migrations = opts.Migrations[:1]
+	data = map[string]interface{}{"client-id": "a"}
+	if !migrate.Stamp(data, opts) || data["schema-version"] != 1 {
+		t.Errorf("expected version-only migrations to be stamped: %v", data)
+	}
+	if migrate.Stamp(map[string]interface{}{"schema-version": 5}, opts) {
+		t.Error("expected a newer version to be left alone")
+	}
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "default.yaml")
+	original := "hoge:\n    fuga: x\n"
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data := func() map[string]interface{} {
+		return map[string]interface{}{"hoge": map[string]interface{}{"fuga": "x"}}
+	}
+
+	opts, out := newOptions()
+	opts.DryRun = true
+	if err := migrate.File(path, data(), opts); err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "-hoge:\n+common:\n     fuga: x\n+schema-version: 2\n") {
+		t.Errorf("unexpected diff:\n%s", out)
+	}
+	if b, _ := os.ReadFile(path); string(b) != original {
+		t.Errorf("dry run modified the file:\n%s", b)
+	}
+
+	opts.DryRun = false
+	if err := migrate.File(path, data(), opts); err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	if b, _ := os.ReadFile(path + migrate.BackupSuffix); string(b) != original {
+		t.Errorf("backup = %q; want original content", b)
+	}
+	if b, _ := os.ReadFile(path); !strings.Contains(string(b), "schema-version: 2") {
+		t.Errorf("migrated file:\n%s", b)
+	}
+
+	out.Reset()
+	if err := migrate.File(path, map[string]interface{}{"schema-version": 2}, opts); err != nil {
+		t.Fatalf("File failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("expected no diff for an up-to-date file, got:\n%s", out)
+	}
+}
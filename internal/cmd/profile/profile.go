@@ -0,0 +1,326 @@
+// Package profile implements the business logic of the `profile` subcommands,
+// which manage the per-profile config files and the stored current profile.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rising3/go-cli/internal/cmd/configure"
+	"github.com/rising3/go-cli/internal/cmd/migrate"
+	"github.com/rising3/go-cli/internal/configfile"
+)
+
+// CurrentFile is the name of the file, inside the config directory,
+// that records the profile selected with `profile use`.
+const CurrentFile = "current-profile"
+
+// Options represents the configuration shared by the profile subcommands.
+type Options struct {
+	Dir       string    // Dir is the directory holding the profile files
+	Ext       string    // Ext is the file extension of new profile files, without the dot
+	Exts      []string  // Exts lists the recognized extensions in lookup order; only Ext when empty
+	Output    io.Writer // Output is the standard output stream for listings
+	ErrOutput io.Writer // ErrOutput is the error output stream for messages
+}
+
+// Path returns the path of the profile file for name: the first existing
+// file with one of the recognized extensions, or the path with opts.Ext
+// when there is none.
+func Path(name string, opts Options) string {
+	for _, ext := range extensions(opts) {
+		path := filepath.Join(opts.Dir, name+"."+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(opts.Dir, name+"."+opts.Ext)
+}
+
+// extensions returns the recognized profile file extensions.
+func extensions(opts Options) []string {
+	if len(opts.Exts) == 0 {
+		return []string{opts.Ext}
+	}
+	return opts.Exts
+}
+
+// Exists reports whether the profile file for name exists.
+func Exists(name string, opts Options) bool {
+	_, err := os.Stat(Path(name, opts))
+	return err == nil
+}
+
+// ValidateName returns an error if name cannot be used as a profile name.
+func ValidateName(name string) error {
+	if name == "" {
+		return errors.New("profile name must not be empty")
+	}
+	if strings.HasPrefix(name, ".") || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid profile name: %q", name)
+	}
+	return nil
+}
+
+// Names returns the sorted names of all profiles found in opts.Dir.
+// A missing directory yields no profiles.
+func Names(opts Options) ([]string, error) {
+	entries, err := os.ReadDir(opts.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		for _, ext := range extensions(opts) {
+			name, ok := strings.CutSuffix(e.Name(), "."+ext)
+			if ok && name != "" && !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// List writes every profile name to opts.Output, marking the active profile
+// with "*" and the source it was selected from (flag, env or stored).
+// An active profile without a file is listed as well so that a mistyped
+// --profile is visible.
+func List(active, source string, opts Options) error {
+	names, err := Names(opts)
+	if err != nil {
+		return err
+	}
+	found := false
+	for _, name := range names {
+		if name == active {
+			found = true
+			if _, err := fmt.Fprintln(opts.Output, activeLine(name, source, "")); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := fmt.Fprintln(opts.Output, "  "+name); err != nil {
+			return err
+		}
+	}
+	if !found && active != "" {
+		_, err := fmt.Fprintln(opts.Output, activeLine(active, source, "not found"))
+		return err
+	}
+	return nil
+}
+
+// activeLine formats the listing line of the active profile.
+func activeLine(name, source, note string) string {
+	var details []string
+	if source != "" {
+		details = append(details, source)
+	}
+	if note != "" {
+		details = append(details, note)
+	}
+	if len(details) == 0 {
+		return "* " + name
+	}
+	return "* " + name + " (" + strings.Join(details, ", ") + ")"
+}
+
+// Create writes content as the new profile name with extension opts.Ext.
+// It fails if the profile exists.
+func Create(name string, content []byte, opts Options) error {
+	return create(name, opts.Ext, content, opts)
+}
+
+// create writes content as the new profile name with the given extension.
+func create(name, ext string, content []byte, opts Options) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	target := filepath.Join(opts.Dir, name+"."+ext)
+	err := configure.WithLock(target, func() error {
+		if Exists(name, opts) {
+			return fmt.Errorf("profile already exists: %s", name)
+		}
+		return configfile.WriteFile(target, content)
+	})
+	if err != nil {
+		return err
+	}
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintln(opts.ErrOutput, "Created profile:", target)
+	}
+	return nil
+}
+
+// Copy duplicates the profile src as dst, keeping its file format.
+// It fails if dst exists.
+func Copy(src, dst string, opts Options) error {
+	if err := ValidateName(src); err != nil {
+		return err
+	}
+	if err := ValidateName(dst); err != nil {
+		return err
+	}
+	path := Path(src, opts)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("profile not found: %s", src)
+		}
+		return err
+	}
+	return create(dst, strings.TrimPrefix(filepath.Ext(path), "."), content, opts)
+}
+
+// Rename moves the profile oldName to newName, keeping its file format, and
+// keeps the stored current profile pointing at it. The backups of the file
+// move along with it. Both profiles are locked (see configure.WithLocks)
+// while the file is moved, and the move fails rather than overwrite a file
+// of newName. The default profile cannot be renamed.
+func Rename(oldName, newName, defaultName string, opts Options) error {
+	if oldName == defaultName {
+		return fmt.Errorf("cannot rename the %s profile", defaultName)
+	}
+	if err := ValidateName(oldName); err != nil {
+		return err
+	}
+	if err := ValidateName(newName); err != nil {
+		return err
+	}
+	targets := []string{filepath.Join(opts.Dir, oldName+"."+opts.Ext), filepath.Join(opts.Dir, newName+"."+opts.Ext)}
+	err := configure.WithLocks(targets, func() error {
+		if !Exists(oldName, opts) {
+			return fmt.Errorf("profile not found: %s", oldName)
+		}
+		if Exists(newName, opts) {
+			return fmt.Errorf("profile already exists: %s", newName)
+		}
+		oldPath := Path(oldName, opts)
+		newPath := filepath.Join(opts.Dir, newName+filepath.Ext(oldPath))
+		return move(oldPath, newPath)
+	})
+	if err != nil {
+		return err
+	}
+	if current, _ := ReadCurrent(opts); current == oldName {
+		if err := WriteCurrent(newName, opts); err != nil {
+			return err
+		}
+	}
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintln(opts.ErrOutput, "Renamed profile:", oldName, "->", newName)
+	}
+	return nil
+}
+
+// move renames the profile file at path to dst, together with its backups
+// and the backup kept by config migrate. Unlike os.Rename, it fails when
+// dst exists.
+func move(path, dst string) error {
+	if err := os.Link(path, dst); err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return fmt.Errorf("profile already exists: %s", dst)
+		}
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	if err := configfile.MoveBackups(path, dst); err != nil {
+		return err
+	}
+	err := os.Rename(path+migrate.BackupSuffix, dst+migrate.BackupSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// Delete removes the profile name, keeping a backup of its file (see
+// configfile.Remove), and clears the stored current profile if it pointed at
+// it. The default profile cannot be deleted.
+func Delete(name, defaultName string, opts Options) error {
+	if name == defaultName {
+		return fmt.Errorf("cannot delete the %s profile", defaultName)
+	}
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	err := configure.WithLock(Path(name, opts), func() error {
+		if !Exists(name, opts) {
+			return fmt.Errorf("profile not found: %s", name)
+		}
+		return configfile.Remove(Path(name, opts))
+	})
+	if err != nil {
+		return err
+	}
+	if current, _ := ReadCurrent(opts); current == name {
+		if err := ClearCurrent(opts); err != nil {
+			return err
+		}
+	}
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintln(opts.ErrOutput, "Deleted profile:", name)
+	}
+	return nil
+}
+
+// Use records name as the current profile. The profile file must exist.
+func Use(name string, opts Options) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if !Exists(name, opts) {
+		return fmt.Errorf("profile not found: %s", name)
+	}
+	if err := WriteCurrent(name, opts); err != nil {
+		return err
+	}
+	if opts.ErrOutput != nil {
+		_, _ = fmt.Fprintln(opts.ErrOutput, "Using profile:", name)
+	}
+	return nil
+}
+
+// ReadCurrent returns the stored current profile, or "" if none is stored.
+func ReadCurrent(opts Options) (string, error) {
+	b, err := os.ReadFile(filepath.Join(opts.Dir, CurrentFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// WriteCurrent stores name as the current profile.
+func WriteCurrent(name string, opts Options) error {
+	return configfile.WriteAtomic(filepath.Join(opts.Dir, CurrentFile), []byte(name+"\n"))
+}
+
+// ClearCurrent removes the stored current profile.
+func ClearCurrent(opts Options) error {
+	err := os.Remove(filepath.Join(opts.Dir, CurrentFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
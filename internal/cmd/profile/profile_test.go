@@ -0,0 +1,238 @@
+package profile_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/cmd/profile"
+	"github.com/rising3/go-cli/internal/configfile"
+)
+
+func newOptions(t *testing.T) (profile.Options, *bytes.Buffer) {
+	t.Helper()
+	var out bytes.Buffer
+	return profile.Options{
+		Dir:       t.TempDir(),
+		Ext:       "yaml",
+		Output:    &out,
+		ErrOutput: &bytes.Buffer{},
+	}, &out
+}
+
+func writeProfile(t *testing.T, opts profile.Options, name string) {
+	t.Helper()
+	if err := os.WriteFile(profile.Path(name, opts), []byte("client-id: "+name+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestList_MarksActiveProfile(t *testing.T) {
+	opts, out := newOptions(t)
+	writeProfile(t, opts, "default")
+	writeProfile(t, opts, "prod")
+	// non-profile files are ignored
+	if err := os.WriteFile(filepath.Join(opts.Dir, profile.CurrentFile), []byte("prod\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := profile.List("prod", "stored", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "  default\n* prod (stored)\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestList_ShowsMissingActiveProfile(t *testing.T) {
+	opts, out := newOptions(t)
+	writeProfile(t, opts, "default")
+
+	if err := profile.List("typo", "flag", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "  default\n* typo (flag, not found)\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCreate_FailsWhenExists(t *testing.T) {
+	opts, _ := newOptions(t)
+	if err := profile.Create("dev", []byte("a: 1\n"), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := profile.Create("dev", []byte("a: 2\n"), opts); err == nil {
+		t.Error("expected error when profile exists")
+	}
+	if err := profile.Create("../evil", []byte("a: 2\n"), opts); err == nil {
+		t.Error("expected error for invalid name")
+	}
+}
+
+func TestCopy(t *testing.T) {
+	opts, _ := newOptions(t)
+	writeProfile(t, opts, "prod")
+
+	if err := profile.Copy("prod", "staging", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, _ := os.ReadFile(profile.Path("staging", opts))
+	if string(b) != "client-id: prod\n" {
+		t.Errorf("copied content = %q", b)
+	}
+	if err := profile.Copy("missing", "other", opts); err == nil {
+		t.Error("expected error for missing source profile")
+	}
+}
+
+func TestRename_UpdatesCurrent(t *testing.T) {
+	opts, _ := newOptions(t)
+	writeProfile(t, opts, "default")
+	writeProfile(t, opts, "prod")
+	if err := profile.Use("prod", opts); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+
+	if err := profile.Rename("prod", "production", "default", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if profile.Exists("prod", opts) || !profile.Exists("production", opts) {
+		t.Error("expected profile file to be renamed")
+	}
+	if current, _ := profile.ReadCurrent(opts); current != "production" {
+		t.Errorf("current = %q, want %q", current, "production")
+	}
+	if err := profile.Rename("default", "base", "default", opts); err == nil {
+		t.Error("expected error when renaming the default profile")
+	}
+}
+
+func TestRename_MovesBackupsAndKeepsExisting(t *testing.T) {
+	opts, _ := newOptions(t)
+	writeProfile(t, opts, "prod")
+	writeProfile(t, opts, "qa")
+	if err := configfile.WriteFile(profile.Path("prod", opts), []byte("client-id: v2\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(profile.Path("prod", opts)+".bak", []byte("migrated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"qa", "prod"} {
+		if err := profile.Rename("prod", name, "default", opts); err == nil {
+			t.Errorf("expected an error renaming prod to the existing profile %s", name)
+		}
+	}
+	if b, _ := os.ReadFile(profile.Path("qa", opts)); string(b) != "client-id: qa\n" {
+		t.Errorf("expected qa to be kept, got %q", b)
+	}
+
+	if err := profile.Rename("prod", "production", "default", opts); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	oldPath, newPath := filepath.Join(opts.Dir, "prod.yaml"), filepath.Join(opts.Dir, "production.yaml")
+	if backups, _ := configfile.Backups(oldPath); len(backups) != 0 {
+		t.Errorf("expected no backups left for prod, got %v", backups)
+	}
+	backups, _ := configfile.Backups(newPath)
+	if len(backups) != 1 {
+		t.Fatalf("expected the backup to move along, got %v", backups)
+	}
+	if b, _ := os.ReadFile(backups[0].Path); string(b) != "client-id: prod\n" {
+		t.Errorf("backup = %q", b)
+	}
+	if _, err := os.Stat(newPath + ".bak"); err != nil {
+		t.Errorf("expected the migrate backup to move along: %v", err)
+	}
+}
+
+func TestDelete_ClearsCurrent(t *testing.T) {
+	opts, _ := newOptions(t)
+	writeProfile(t, opts, "prod")
+	if err := profile.Use("prod", opts); err != nil {
+		t.Fatalf("Use failed: %v", err)
+	}
+
+	if err := profile.Delete("prod", "default", opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if current, _ := profile.ReadCurrent(opts); current != "" {
+		t.Errorf("expected current profile to be cleared, got %q", current)
+	}
+	if err := profile.Delete("default", "default", opts); err == nil {
+		t.Error("expected error when deleting the default profile")
+	}
+}
+
+func TestUse_RequiresExistingProfile(t *testing.T) {
+	opts, _ := newOptions(t)
+	if err := profile.Use("missing", opts); err == nil {
+		t.Error("expected error for missing profile")
+	}
+}
+
+func TestMultipleFormats(t *testing.T) {
+	opts, out := newOptions(t)
+	opts.Exts = []string{"yaml", "json", "env"}
+	writeProfile(t, opts, "prod")
+	if err := os.WriteFile(filepath.Join(opts.Dir, "dev.json"), []byte("{}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(opts.Dir, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := profile.Path("dev", opts); got != filepath.Join(opts.Dir, "dev.json") {
+		t.Errorf("Path(dev) = %s", got)
+	}
+	if err := profile.List("", "", opts); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "  dev\n  prod\n" {
+		t.Errorf("list output = %q", out.String())
+	}
+
+	if err := profile.Copy("dev", "qa", opts); err != nil {
+		t.Fatalf("Copy failed: %v", err)
+	}
+	if err := profile.Rename("qa", "uat", "default", opts); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(opts.Dir, "uat.json")); err != nil {
+		t.Errorf("expected copied and renamed profile to keep its format: %v", err)
+	}
+}
+
+func TestInvalidNames_StayInDir(t *testing.T) {
+	opts, _ := newOptions(t)
+	opts.Dir = filepath.Join(opts.Dir, "profiles")
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	victim := filepath.Join(filepath.Dir(opts.Dir), "victim.yaml")
+	if err := os.WriteFile(victim, []byte("a: 1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := profile.Delete("../victim", "default", opts); err == nil {
+		t.Error("expected Delete to reject a path")
+	}
+	if err := profile.Use("../victim", opts); err == nil {
+		t.Error("expected Use to reject a path")
+	}
+	if err := profile.Copy("../victim", "copy", opts); err == nil {
+		t.Error("expected Copy to reject a path as source")
+	}
+	if err := profile.Rename("../victim", "moved", "default", opts); err == nil {
+		t.Error("expected Rename to reject a path as old name")
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("expected %s to be left alone: %v", victim, err)
+	}
+	if current, _ := profile.ReadCurrent(opts); current != "" {
+		t.Errorf("expected no current profile, got %q", current)
+	}
+}
@@ -0,0 +1,212 @@
+// Package configfile writes config files atomically and keeps rotating,
+// timestamped backups of the versions they replace.
+//
+// A backup of dev.yaml is stored next to it as
+// dev.yaml.<version>.bak, where the version is the UTC time the backup was
+// taken in VersionLayout.
+package configfile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rising3/go-cli/internal/fileperm"
+)
+
+// VersionLayout is the time layout of backup versions.
+const VersionLayout = "20060102T150405.000Z"
+
+// backupSuffix ends the name of every backup file.
+const backupSuffix = ".bak"
+
+// Keep is the number of backups kept per file; older ones are removed when
+// a new backup is taken.
+var Keep = 5
+
+// now returns the current time; it is a variable for testing.
+var now = time.Now
+
+// Backup is a saved version of a config file.
+type Backup struct {
+	Path     string    // Path is the backup file
+	Original string    // Original is the file the backup was taken of
+	Version  string    // Version identifies the backup among those of Original
+	Time     time.Time // Time is when the backup was taken
+}
+
+// WriteFile replaces the file at path with content, backing up the current
+// version first (see Backups). The parent directory is created with
+// fileperm.DirMode when needed.
+func WriteFile(path string, content []byte) error {
+	if _, err := backup(path); err != nil {
+		return err
+	}
+	return WriteAtomic(path, content)
+}
+
+// WriteAtomic replaces the file at path with content without taking a
+// backup. The content is written to a temporary file in the same directory,
+// synced and renamed over path, so readers and crashes see either the old or
+// the new file, never a partial one. New files get fileperm.FileMode; an
+// existing file keeps its permissions.
+func WriteAtomic(path string, content []byte) error {
+	dir := filepath.Dir(path)
+	if err := fileperm.MkdirAll(dir); err != nil {
+		return err
+	}
+	mode := fileperm.FileMode
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// Remove backs up the file at path and removes it, so that it can be
+// restored. A missing file is not an error.
+func Remove(path string) error {
+	if _, err := backup(path); err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// RemoveBackups removes every backup of the file at path and returns how
+// many were removed. Writers replacing secrets use it, together with
+// WriteAtomic, so that no backup keeps the values they replaced.
+func RemoveBackups(path string) (int, error) {
+	backups, err := Backups(path)
+	if err != nil {
+		return 0, err
+	}
+	for i, b := range backups {
+		if err := os.Remove(b.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return i, err
+		}
+	}
+	return len(backups), nil
+}
+
+// MoveBackups renames every backup of the file at path to a backup of the
+// file at dst, keeping its version, so that the backups follow a renamed
+// file.
+func MoveBackups(path, dst string) error {
+	backups, err := Backups(path)
+	if err != nil {
+		return err
+	}
+	for _, b := range backups {
+		if err := os.Rename(b.Path, dst+"."+b.Version+backupSuffix); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Backups returns the backups of the files at paths, newest first.
+func Backups(paths ...string) ([]Backup, error) {
+	var backups []Backup
+	for _, path := range paths {
+		entries, err := os.ReadDir(filepath.Dir(path))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		prefix := filepath.Base(path) + "."
+		for _, e := range entries {
+			version, ok := strings.CutPrefix(e.Name(), prefix)
+			if !ok || e.IsDir() {
+				continue
+			}
+			version, ok = strings.CutSuffix(version, backupSuffix)
+			t, err := time.Parse(VersionLayout, version)
+			if !ok || err != nil {
+				continue
+			}
+			backups = append(backups, Backup{Path: filepath.Join(filepath.Dir(path), e.Name()), Original: path, Version: version, Time: t})
+		}
+	}
+	sort.SliceStable(backups, func(i, j int) bool { return backups[i].Time.After(backups[j].Time) })
+	return backups, nil
+}
+
+// Restore writes the content of b back to b.Original through WriteFile, so
+// the version it replaces is backed up in turn.
+func Restore(b Backup) error {
+	content, err := os.ReadFile(b.Path)
+	if err != nil {
+		return err
+	}
+	return WriteFile(b.Original, content)
+}
+
+// backup copies the file at path to a new backup and removes the backups
+// beyond Keep. It returns the backup, or nil when path does not exist.
+func backup(path string) (*Backup, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// Versions have millisecond precision; move on to a free one when several
+	// backups are taken within the same millisecond.
+	b := &Backup{Original: path, Time: now().UTC().Truncate(time.Millisecond)}
+	for {
+		b.Version = b.Time.Format(VersionLayout)
+		b.Path = path + "." + b.Version + backupSuffix
+		if _, err := os.Lstat(b.Path); err != nil {
+			break
+		}
+		b.Time = b.Time.Add(time.Millisecond)
+	}
+	if err := WriteAtomic(b.Path, content); err != nil {
+		return nil, fmt.Errorf("back up %s: %w", path, err)
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		return nil, err
+	}
+	for i := Keep; i < len(backups); i++ {
+		if err := os.Remove(backups[i].Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return b, nil
+}
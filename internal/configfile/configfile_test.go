@@ -0,0 +1,149 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeClock makes now return distinct, increasing times.
+func fakeClock(t *testing.T) {
+	t.Helper()
+	clock := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	orig := now
+	now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	t.Cleanup(func() { now = orig })
+}
+
+func readString(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestWriteAtomic(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mycli")
+	path := filepath.Join(dir, "dev.yaml")
+	if err := WriteAtomic(path, []byte("a: 1\n")); err != nil {
+		t.Fatalf("WriteAtomic failed: %v", err)
+	}
+	if got := readString(t, path); got != "a: 1\n" {
+		t.Errorf("content = %q", got)
+	}
+	if runtime.GOOS != "windows" {
+		if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+			t.Errorf("mode = %v; want 0600", fi.Mode().Perm())
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected no temporary or backup file, got %v", entries)
+	}
+}
+
+func TestWriteFile_RotatesBackups(t *testing.T) {
+	fakeClock(t)
+	orig := Keep
+	Keep = 2
+	t.Cleanup(func() { Keep = orig })
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dev.yaml")
+	for _, content := range []string{"v1", "v2", "v3", "v4"} {
+		if err := WriteFile(path, []byte(content)); err != nil {
+			t.Fatalf("WriteFile(%s) failed: %v", content, err)
+		}
+	}
+	// unrelated files next to the profile are not backups
+	if err := os.WriteFile(path+".bak", []byte("migrate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := Backups(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("expected %d backups, got %+v", Keep, backups)
+	}
+	if got := readString(t, backups[0].Path); got != "v3" {
+		t.Errorf("newest backup = %q; want v3", got)
+	}
+	if got := readString(t, backups[1].Path); got != "v2" {
+		t.Errorf("oldest backup = %q; want v2", got)
+	}
+	if backups[0].Original != path || backups[0].Version != "20250102T150408.000Z" {
+		t.Errorf("unexpected backup: %+v", backups[0])
+	}
+
+	if err := Restore(backups[1]); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	if got := readString(t, path); got != "v2" {
+		t.Errorf("restored content = %q; want v2", got)
+	}
+	backups, _ = Backups(path)
+	if got := readString(t, backups[0].Path); got != "v4" {
+		t.Errorf("expected the replaced version to be backed up, got %q", got)
+	}
+}
+
+func TestRemove(t *testing.T) {
+	fakeClock(t)
+	path := filepath.Join(t.TempDir(), "dev.yaml")
+	if err := WriteFile(path, []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := Remove(path); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", path)
+	}
+	backups, err := Backups(path)
+	if err != nil || len(backups) != 1 || readString(t, backups[0].Path) != "v1" {
+		t.Errorf("expected a backup of the removed file, got %+v, %v", backups, err)
+	}
+	if err := Remove(path); err != nil {
+		t.Errorf("expected no error for a missing file, got %v", err)
+	}
+}
+
+func TestRemoveBackups(t *testing.T) {
+	fakeClock(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dev.yaml")
+	for _, v := range []string{"v1", "v2", "v3"} {
+		if err := WriteFile(path, []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	other := filepath.Join(dir, "prod.yaml")
+	for _, v := range []string{"p1", "p2"} {
+		if err := WriteFile(other, []byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n, err := RemoveBackups(path); n != 2 || err != nil {
+		t.Fatalf("RemoveBackups = %d, %v; want 2", n, err)
+	}
+	if backups, _ := Backups(path); len(backups) != 0 {
+		t.Errorf("expected no backup left, got %+v", backups)
+	}
+	if readString(t, path) != "v3" {
+		t.Error("expected the file itself to be kept")
+	}
+	if backups, _ := Backups(other); len(backups) != 1 {
+		t.Errorf("expected the backups of other files to be kept, got %+v", backups)
+	}
+}
@@ -0,0 +1,6 @@
+//go:build !unix
+
+package configfile
+
+// syncDir is a no-op on this platform, where directories cannot be synced.
+func syncDir(string) error { return nil }
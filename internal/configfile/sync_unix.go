@@ -0,0 +1,15 @@
+//go:build unix
+
+package configfile
+
+import "os"
+
+// syncDir flushes the directory entry of a renamed file to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = d.Close() }()
+	return d.Sync()
+}
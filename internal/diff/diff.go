@@ -0,0 +1,144 @@
+// Package diff produces line-based unified diffs of text files.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Context is the number of unchanged lines shown around every change.
+const Context = 3
+
+// op is a line of an edit script: ' ' kept, '-' removed or '+' added.
+type op struct {
+	kind byte
+	line string
+}
+
+// Unified returns the unified diff turning a into b, with the file names
+// aName and bName in the header. It returns "" when a and b are equal.
+func Unified(aName, bName string, a, b []byte) string {
+	if string(a) == string(b) {
+		return ""
+	}
+	ops := edits(splitLines(string(a)), splitLines(string(b)))
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", aName, bName)
+	for _, h := range hunks(ops) {
+		writeHunk(&sb, ops, h)
+	}
+	return sb.String()
+}
+
+// splitLines splits s into lines without their terminating newline.
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// edits computes a shortest edit script from a to b using the longest
+// common subsequence of their lines.
+func edits(a, b []string) []op {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var ops []op
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			ops = append(ops, op{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, op{'-', a[i]})
+			i++
+		default:
+			ops = append(ops, op{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		ops = append(ops, op{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		ops = append(ops, op{'+', b[j]})
+	}
+	return ops
+}
+
+// hunk is a range [start, end) of an edit script.
+type hunk struct{ start, end int }
+
+// hunks groups the changes of ops into ranges padded with Context kept
+// lines, merging ranges whose context overlaps.
+func hunks(ops []op) []hunk {
+	var hs []hunk
+	for i, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		start, end := max(i-Context, 0), min(i+Context+1, len(ops))
+		if n := len(hs); n > 0 && start <= hs[n-1].end {
+			hs[n-1].end = end
+			continue
+		}
+		hs = append(hs, hunk{start, end})
+	}
+	return hs
+}
+
+// writeHunk writes the header and lines of h.
+func writeHunk(sb *strings.Builder, ops []op, h hunk) {
+	// Line numbers of the first line of the hunk in a and b.
+	aLine, bLine := 1, 1
+	for _, o := range ops[:h.start] {
+		if o.kind != '+' {
+			aLine++
+		}
+		if o.kind != '-' {
+			bLine++
+		}
+	}
+	aCount, bCount := 0, 0
+	for _, o := range ops[h.start:h.end] {
+		if o.kind != '+' {
+			aCount++
+		}
+		if o.kind != '-' {
+			bCount++
+		}
+	}
+	fmt.Fprintf(sb, "@@ -%s +%s @@\n", lineRange(aLine, aCount), lineRange(bLine, bCount))
+	for _, o := range ops[h.start:h.end] {
+		sb.WriteByte(o.kind)
+		sb.WriteString(o.line)
+		sb.WriteByte('\n')
+	}
+}
+
+// lineRange formats a hunk range; an empty range refers to the line before it.
+func lineRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,66 @@
+package diff_test
+
+import (
+	"testing"
+
+	"github.com/rising3/go-cli/internal/diff"
+)
+
+func TestUnified_Equal(t *testing.T) {
+	if got := diff.Unified("a", "b", []byte("x\n"), []byte("x\n")); got != "" {
+		t.Errorf("expected no diff, got:\n%s", got)
+	}
+}
+
+func TestUnified_Change(t *testing.T) {
+	a := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	b := []byte("1\n2\n3\n4\nfive\n6\n7\n8\n9\n10\n11\n")
+	want := `--- old
++++ new
+@@ -2,9 +2,10 @@
+ 2
+ 3
+ 4
+-5
++five
+ 6
+ 7
+ 8
+ 9
+ 10
++11
+`
+	if got := diff.Unified("old", "new", a, b); got != want {
+		t.Errorf("Unified =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnified_SeparateHunks(t *testing.T) {
+	a := []byte("a\n1\n2\n3\n4\n5\n6\n7\n8\nz\n")
+	b := []byte("A\n1\n2\n3\n4\n5\n6\n7\n8\nZ\n")
+	want := `--- f
++++ f
+@@ -1,4 +1,4 @@
+-a
++A
+ 1
+ 2
+ 3
+@@ -7,4 +7,4 @@
+ 6
+ 7
+ 8
+-z
++Z
+`
+	if got := diff.Unified("f", "f", a, b); got != want {
+		t.Errorf("Unified =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnified_NewFile(t *testing.T) {
+	want := "--- /dev/null\n+++ f\n@@ -0,0 +1,2 @@\n+a\n+b\n"
+	if got := diff.Unified("/dev/null", "f", nil, []byte("a\nb\n")); got != want {
+		t.Errorf("Unified =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,40 @@
+// Package fileperm creates config files with private permissions and audits
+// the permissions and ownership of existing ones.
+package fileperm
+
+import (
+	"fmt"
+	"os"
+)
+
+// Permissions of the files and directories holding configuration. Config
+// files may contain secrets, so only their owner may read them.
+const (
+	FileMode os.FileMode = 0o600
+	DirMode  os.FileMode = 0o700
+)
+
+// Issue describes why a file is not private to the current user.
+type Issue struct {
+	Path     string      // Path is the file concerned
+	Mode     os.FileMode // Mode is the permission bits of the file
+	Readable bool        // Readable reports that the group or others may read the file
+	OwnerUID int         // OwnerUID is the owner when it is another user, -1 otherwise
+}
+
+func (i *Issue) String() string {
+	switch {
+	case i.OwnerUID >= 0 && i.Readable:
+		return fmt.Sprintf("%s is owned by uid %d and readable by group or others (mode %04o)", i.Path, i.OwnerUID, i.Mode)
+	case i.OwnerUID >= 0:
+		return fmt.Sprintf("%s is owned by uid %d", i.Path, i.OwnerUID)
+	default:
+		return fmt.Sprintf("%s is readable by group or others (mode %04o)", i.Path, i.Mode)
+	}
+}
+
+// MkdirAll creates dir and its missing parents with DirMode. Existing
+// directories are left unchanged.
+func MkdirAll(dir string) error {
+	return os.MkdirAll(dir, DirMode)
+}
@@ -0,0 +1,19 @@
+//go:build !unix
+
+package fileperm
+
+import "os"
+
+// Check returns the Issue of the file or directory at path. Permission bits
+// and owners are not meaningful on this platform, so it only reports errors
+// from stat.
+func Check(path string) (*Issue, error) {
+	_, err := os.Stat(path)
+	return nil, err
+}
+
+// Fix is a no-op on this platform; see Check.
+func Fix(path string) (bool, error) {
+	_, err := os.Stat(path)
+	return false, err
+}
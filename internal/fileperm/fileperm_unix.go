@@ -0,0 +1,48 @@
+//go:build unix
+
+package fileperm
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Check returns the Issue of the file or directory at path, or nil when it is
+// owned by the current user or root and cannot be read by anyone else.
+func Check(path string) (*Issue, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	issue := &Issue{Path: path, Mode: fi.Mode().Perm(), Readable: fi.Mode().Perm()&0o044 != 0, OwnerUID: -1}
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Uid != 0 && int(st.Uid) != os.Getuid() {
+		issue.OwnerUID = int(st.Uid)
+	}
+	if !issue.Readable && issue.OwnerUID < 0 {
+		return nil, nil
+	}
+	return issue, nil
+}
+
+// Fix makes the file or directory at path private: it removes the group and
+// other permission bits and, when it is owned by another user, tries to take
+// ownership, which normally requires root. It reports whether anything was
+// changed.
+func Fix(path string) (bool, error) {
+	issue, err := Check(path)
+	if err != nil || issue == nil {
+		return false, err
+	}
+	if issue.OwnerUID >= 0 {
+		if err := os.Chown(path, os.Getuid(), os.Getgid()); err != nil {
+			return false, fmt.Errorf("%s is owned by uid %d: %w", path, issue.OwnerUID, err)
+		}
+	}
+	if issue.Mode&0o077 != 0 {
+		if err := os.Chmod(path, issue.Mode&^0o077); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,64 @@
+//go:build unix
+
+package fileperm_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/fileperm"
+)
+
+func TestMkdirAll_Private(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mycli")
+	if err := fileperm.MkdirAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "default.yaml")
+	if err := os.WriteFile(path, []byte("client-secret: x\n"), fileperm.FileMode); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]os.FileMode{dir: fileperm.DirMode, path: fileperm.FileMode} {
+		fi, err := os.Stat(p)
+		if err != nil || fi.Mode().Perm() != want {
+			t.Errorf("mode of %s = %v, %v; want %v", p, fi.Mode().Perm(), err, want)
+		}
+		if issue, err := fileperm.Check(p); issue != nil || err != nil {
+			t.Errorf("Check(%s) = %v, %v; want no issue", p, issue, err)
+		}
+	}
+}
+
+func TestCheckAndFix(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "default.yaml")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	issue, err := fileperm.Check(path)
+	if err != nil || issue == nil || !issue.Readable || issue.OwnerUID != -1 {
+		t.Fatalf("Check = %+v, %v; want a readable issue", issue, err)
+	}
+	if got, want := issue.String(), path+" is readable by group or others (mode 0644)"; got != want {
+		t.Errorf("String() = %q; want %q", got, want)
+	}
+
+	if fixed, err := fileperm.Fix(path); !fixed || err != nil {
+		t.Fatalf("Fix = %v, %v; want fixed", fixed, err)
+	}
+	if fi, _ := os.Stat(path); fi.Mode().Perm() != 0o600 {
+		t.Errorf("mode after Fix = %v; want 0600", fi.Mode().Perm())
+	}
+	if fixed, err := fileperm.Fix(path); fixed || err != nil {
+		t.Errorf("second Fix = %v, %v; want nothing to fix", fixed, err)
+	}
+
+	if _, err := fileperm.Check(filepath.Join(dir, "missing.yaml")); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error, got %v", err)
+	}
+}
@@ -0,0 +1,254 @@
+// Package interpolate expands ${...} placeholders in configuration values.
+//
+// A placeholder is one of
+//
+//	${section.key}  the (expanded and resolved) value of another dotted key
+//	${env:NAME}     the environment variable NAME
+//	${name}         a variable passed to Expand, such as ${profile}
+//
+// Variables take precedence over keys of the same name. "$${" stands for a
+// literal "${". Only string values are expanded.
+package interpolate
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Options represents the sources placeholders are resolved from.
+type Options struct {
+	Vars      map[string]string                // Vars are the named variables such as "profile"
+	LookupEnv func(name string) (string, bool) // LookupEnv resolves ${env:NAME}; typically os.LookupEnv
+
+	// Resolve, when set, maps the expanded value of every string key to the
+	// value that is stored and substituted for ${key}, such as the value a
+	// secret reference points to. An error fails the key.
+	Resolve func(key, value string) (string, error)
+}
+
+// Expand returns a deep copy of settings with the placeholders in every
+// string value expanded. It fails on undefined keys or variables, on keys
+// referring to a section and on reference cycles; errors name the offending
+// key but never contain values, and keys that fail keep their raw value.
+func Expand(settings map[string]interface{}, opts Options) (map[string]interface{}, error) {
+	e := &expander{settings: settings, opts: opts, done: map[string]string{}}
+	out := copyMap(settings)
+
+	var errs []error
+	for _, key := range leafKeys(settings) {
+		raw, _ := lookup(settings, key)
+		if _, ok := raw.(string); !ok {
+			continue
+		}
+		v, err := e.key(key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+		set(out, key, v)
+	}
+	return out, errors.Join(errs...)
+}
+
+// Sources returns the sorted keys that the placeholders of the dotted key
+// refer to, directly or through other keys, so that callers can tell which
+// values an expanded value is made of. Environment variables and variables
+// in opts are not keys and are left out.
+func Sources(settings map[string]interface{}, key string, opts Options) []string {
+	seen := map[string]bool{}
+	var walk func(key string)
+	walk = func(key string) {
+		raw, _ := lookup(settings, key)
+		s, ok := raw.(string)
+		if !ok {
+			return
+		}
+		for _, name := range placeholders(s) {
+			if _, isVar := opts.Vars[name]; isVar || name == "" || strings.HasPrefix(name, "env:") {
+				continue
+			}
+			if name = strings.ToLower(name); !seen[name] {
+				seen[name] = true
+				walk(name)
+			}
+		}
+	}
+	walk(key)
+
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// placeholders returns the names of the ${...} placeholders of s, skipping
+// escaped ones and stopping at an unterminated one.
+func placeholders(s string) []string {
+	var names []string
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return names
+			}
+			names = append(names, strings.TrimSpace(s[i+2:i+2+end]))
+			i += end + 3
+		default:
+			i++
+		}
+	}
+	return names
+}
+
+// expander expands keys of settings, memoizing the results and tracking the
+// keys being expanded to detect cycles.
+type expander struct {
+	settings map[string]interface{}
+	opts     Options
+	done     map[string]string
+	stack    []string
+}
+
+// key returns the expanded value of the dotted key.
+func (e *expander) key(key string) (string, error) {
+	if v, ok := e.done[key]; ok {
+		return v, nil
+	}
+	for i, k := range e.stack {
+		if k == key {
+			cycle := append(append([]string{}, e.stack[i:]...), key)
+			return "", fmt.Errorf("interpolation cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	raw, ok := lookup(e.settings, key)
+	if !ok {
+		return "", fmt.Errorf("undefined key %q", key)
+	}
+	s, isString := raw.(string)
+	if !isString {
+		if _, isSection := raw.(map[string]interface{}); isSection {
+			return "", fmt.Errorf("key %q is a section", key)
+		}
+		return fmt.Sprint(raw), nil
+	}
+
+	e.stack = append(e.stack, key)
+	v, err := e.expand(s)
+	e.stack = e.stack[:len(e.stack)-1]
+	if err == nil && e.opts.Resolve != nil {
+		v, err = e.opts.Resolve(key, v)
+	}
+	if err != nil {
+		return "", err
+	}
+	e.done[key] = v
+	return v, nil
+}
+
+// expand replaces the placeholders of s.
+func (e *expander) expand(s string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			sb.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", errors.New("unterminated ${")
+			}
+			v, err := e.resolve(strings.TrimSpace(s[i+2 : i+2+end]))
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(v)
+			i += end + 3
+		default:
+			sb.WriteByte(s[i])
+			i++
+		}
+	}
+	return sb.String(), nil
+}
+
+// resolve returns the value of the placeholder name.
+func (e *expander) resolve(name string) (string, error) {
+	if env, ok := strings.CutPrefix(name, "env:"); ok {
+		if e.opts.LookupEnv != nil {
+			if v, ok := e.opts.LookupEnv(env); ok {
+				return v, nil
+			}
+		}
+		return "", fmt.Errorf("environment variable %s is not set", env)
+	}
+	if name == "" {
+		return "", errors.New("empty ${}")
+	}
+	if v, ok := e.opts.Vars[name]; ok {
+		return v, nil
+	}
+	return e.key(strings.ToLower(name))
+}
+
+// lookup returns the value under the dotted key in data.
+func lookup(data map[string]interface{}, key string) (interface{}, bool) {
+	var cur interface{} = data
+	for _, part := range strings.Split(key, ".") {
+		m, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+// set stores value under the dotted key of data, whose sections exist.
+func set(data map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		data = data[part].(map[string]interface{})
+	}
+	data[parts[len(parts)-1]] = value
+}
+
+// leafKeys returns the sorted dotted keys of the leaves of data.
+func leafKeys(data map[string]interface{}) []string {
+	var keys []string
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for k, v := range m {
+			if child, ok := v.(map[string]interface{}); ok {
+				walk(prefix+k+".", child)
+				continue
+			}
+			keys = append(keys, prefix+k)
+		}
+	}
+	walk("", data)
+	sort.Strings(keys)
+	return keys
+}
+
+// copyMap returns a deep copy of the sections of data.
+func copyMap(data map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		if child, ok := v.(map[string]interface{}); ok {
+			v = copyMap(child)
+		}
+		out[k] = v
+	}
+	return out
+}
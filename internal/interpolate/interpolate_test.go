@@ -0,0 +1,142 @@
+package interpolate_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/interpolate"
+)
+
+func newOptions() interpolate.Options {
+	env := map[string]string{"HOME": "/home/me"}
+	return interpolate.Options{
+		Vars: map[string]string{"profile": "prod"},
+		LookupEnv: func(name string) (string, bool) {
+			v, ok := env[name]
+			return v, ok
+		},
+	}
+}
+
+func TestExpand(t *testing.T) {
+	settings := map[string]interface{}{
+		"client-id": "${hoge.url}/id",
+		"common": map[string]interface{}{
+			"var1": "api-${profile}.example.com",
+			"var2": 8080,
+		},
+		"hoge": map[string]interface{}{
+			"url":  "https://${Common.Var1}:${common.var2}",
+			"fuga": "${env:HOME}/data",
+			"foo":  map[string]interface{}{"bar": "$${literal} and $$ and $"},
+		},
+	}
+
+	got, err := interpolate.Expand(settings, newOptions())
+	if err != nil {
+		t.Fatalf("Expand failed: %v", err)
+	}
+	if got["client-id"] != "https://api-prod.example.com:8080/id" {
+		t.Errorf("client-id = %v", got["client-id"])
+	}
+	hoge := got["hoge"].(map[string]interface{})
+	if hoge["fuga"] != "/home/me/data" {
+		t.Errorf("hoge.fuga = %v", hoge["fuga"])
+	}
+	if bar := hoge["foo"].(map[string]interface{})["bar"]; bar != "${literal} and $$ and $" {
+		t.Errorf("hoge.foo.bar = %v", bar)
+	}
+	if got["common"].(map[string]interface{})["var2"] != 8080 {
+		t.Error("non-string values must be kept as is")
+	}
+	if settings["client-id"] != "${hoge.url}/id" {
+		t.Error("Expand must not modify its input")
+	}
+}
+
+func TestExpand_Resolve(t *testing.T) {
+	settings := map[string]interface{}{
+		"ref":  "env:${profile}",
+		"url":  "x-${ref}",
+		"bad":  "exec:fail",
+		"uses": "${bad}",
+	}
+	opts := newOptions()
+	opts.Resolve = func(key, value string) (string, error) {
+		switch value {
+		case "env:prod":
+			return "resolved", nil
+		case "exec:fail":
+			return "", errors.New("cannot resolve")
+		}
+		return value, nil
+	}
+
+	got, err := interpolate.Expand(settings, opts)
+	if got["ref"] != "resolved" || got["url"] != "x-resolved" {
+		t.Errorf("ref = %v, url = %v", got["ref"], got["url"])
+	}
+	if err == nil || !strings.Contains(err.Error(), "bad: cannot resolve") || !strings.Contains(err.Error(), "uses: cannot resolve") {
+		t.Errorf("expected bad and uses to fail, got %v", err)
+	}
+}
+
+func TestExpand_Errors(t *testing.T) {
+	tests := map[string]struct {
+		settings map[string]interface{}
+		want     string
+	}{
+		"cycle": {
+			map[string]interface{}{"a": "${b}", "b": "${c}", "c": "${a}"},
+			"a: interpolation cycle: a -> b -> c -> a",
+		},
+		"self": {
+			map[string]interface{}{"a": "x${a}"},
+			"a: interpolation cycle: a -> a",
+		},
+		"undefined": {
+			map[string]interface{}{"a": "${missing}"},
+			`a: undefined key "missing"`,
+		},
+		"section": {
+			map[string]interface{}{"a": "${s}", "s": map[string]interface{}{"k": "v"}},
+			`a: key "s" is a section`,
+		},
+		"env": {
+			map[string]interface{}{"a": "${env:NOPE}"},
+			"a: environment variable NOPE is not set",
+		},
+		"unterminated": {
+			map[string]interface{}{"a": "${secret"},
+			"a: unterminated ${",
+		},
+	}
+	for name, tt := range tests {
+		_, err := interpolate.Expand(tt.settings, newOptions())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %v; want %q", name, err, tt.want)
+		}
+	}
+}
+
+func TestSources(t *testing.T) {
+	settings := map[string]interface{}{
+		"client-id": "${hoge.url}/${profile}",
+		"common":    map[string]interface{}{"var1": "${env:HOME}", "var2": 8080},
+		"hoge": map[string]interface{}{
+			"url":  "https://${Common.Var1}:${common.var2}$${client-id}",
+			"fuga": "${hoge.fuga}",
+		},
+	}
+
+	if got := strings.Join(interpolate.Sources(settings, "client-id", newOptions()), ","); got != "common.var1,common.var2,hoge.url" {
+		t.Errorf("Sources(client-id) = %s", got)
+	}
+	if got := strings.Join(interpolate.Sources(settings, "hoge.fuga", newOptions()), ","); got != "hoge.fuga" {
+		t.Errorf("Sources(hoge.fuga) = %s", got)
+	}
+	if got := interpolate.Sources(settings, "common.var2", newOptions()); len(got) != 0 {
+		t.Errorf("Sources(common.var2) = %v", got)
+	}
+}
@@ -1,8 +1,12 @@
 package proc
 
 import (
+	"bytes"
+	"fmt"
 	"io"
 	"os/exec"
+	"strings"
+	"time"
 )
 
 // ExecCommand is a variable so tests can override the command construction.
@@ -36,3 +40,44 @@ func Run(cmd *exec.Cmd, shouldWait bool, stderr io.Writer) error {
 	}
 	return nil
 }
+
+// Output runs cmd and returns its standard output. The process is killed if
+// it has not exited after timeout (zero means no limit). Unlike Run, failures
+// are returned, including the trimmed standard error of the process.
+func Output(cmd *exec.Cmd, timeout time.Duration) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if cmd.WaitDelay == 0 {
+		// Bound the wait for output pipes held open by orphaned children.
+		cmd.WaitDelay = time.Second
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%w: %s", err, msg)
+			}
+			return nil, err
+		}
+		return stdout.Bytes(), nil
+	case <-expired:
+		_ = cmd.Process.Kill()
+		<-done
+		return nil, fmt.Errorf("timed out after %s", timeout)
+	}
+}
@@ -61,3 +61,21 @@ func TestExecCommandOverrideUsed(t *testing.T) {
 		t.Fatalf("Run returned unexpected error: %v", err)
 	}
 }
+
+func TestOutput(t *testing.T) {
+	out, err := Output(exec.Command("echo", "hello"), time.Second)
+	if err != nil || string(out) != "hello\n" {
+		t.Fatalf("Output = %q, %v", out, err)
+	}
+
+	_, err = Output(exec.Command("sh", "-c", "echo oops >&2; exit 3"), time.Second)
+	if err == nil || !bytes.Contains([]byte(err.Error()), []byte("exit status 3: oops")) {
+		t.Fatalf("expected exit error with stderr, got: %v", err)
+	}
+
+	start := time.Now()
+	_, err = Output(exec.Command("sleep", "5"), 50*time.Millisecond)
+	if err == nil || time.Since(start) > 2*time.Second {
+		t.Fatalf("expected timeout, got %v after %v", err, time.Since(start))
+	}
+}
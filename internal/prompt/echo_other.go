@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package prompt
+
+import "io"
+
+// disableEcho cannot control the terminal on this platform; answers to
+// secret questions are echoed.
+func disableEcho(io.Reader) (func(), bool) {
+	return nil, false
+}
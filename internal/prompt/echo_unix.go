@@ -0,0 +1,59 @@
+//go:build linux || darwin || dragonfly || freebsd || netbsd || openbsd
+
+package prompt
+
+import (
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// disableEcho turns off the echo of the terminal r, if it is one, and
+// returns the function restoring it. The echo is also restored when the
+// process is interrupted or terminated in between.
+func disableEcho(r io.Reader) (func(), bool) {
+	f, ok := r.(*os.File)
+	if !ok {
+		return nil, false
+	}
+	fd := int(f.Fd())
+	state, err := unix.IoctlGetTermios(fd, ioctlGetTermios)
+	if err != nil {
+		return nil, false
+	}
+	silent := *state
+	silent.Lflag &^= unix.ECHO
+	silent.Lflag |= unix.ICANON | unix.ISIG
+	if err := unix.IoctlSetTermios(fd, ioctlSetTermios, &silent); err != nil {
+		return nil, false
+	}
+	restore := func() { _ = unix.IoctlSetTermios(fd, ioctlSetTermios, state) }
+
+	// A signal ending the process while the echo is off would leave the
+	// terminal silent: restore the echo first, then raise the signal again
+	// for its default action.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, unix.SIGINT, unix.SIGTERM, unix.SIGHUP, unix.SIGQUIT)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case sig := <-signals:
+			restore()
+			signal.Stop(signals)
+			_ = unix.Kill(unix.Getpid(), sig.(unix.Signal))
+		case <-done:
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			restore()
+		})
+	}, true
+}
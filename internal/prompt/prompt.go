@@ -0,0 +1,72 @@
+// Package prompt asks questions over stdio.Streams and reads one answer per
+// line, so that interactive commands can also be driven by piped input.
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rising3/go-cli/internal/stdio"
+)
+
+// ErrNoInput is returned when the input ends before an answer is given.
+var ErrNoInput = errors.New("no more input")
+
+// Prompter asks questions on an output stream and reads the answers from
+// an input stream.
+type Prompter struct {
+	in     io.Reader
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+// New returns a Prompter reading from streams.In and writing the questions
+// to streams.Out.
+func New(streams stdio.Streams) *Prompter {
+	return &Prompter{in: streams.In, reader: bufio.NewReader(streams.In), out: streams.Out}
+}
+
+// Ask writes question as is and returns the next input line without its
+// line break.
+func (p *Prompter) Ask(question string) (string, error) {
+	if _, err := io.WriteString(p.out, question); err != nil {
+		return "", err
+	}
+	return p.readLine()
+}
+
+// AskSecret is like Ask, but the answer is not echoed when the input is a
+// terminal.
+func (p *Prompter) AskSecret(question string) (string, error) {
+	if _, err := io.WriteString(p.out, question); err != nil {
+		return "", err
+	}
+	restore, ok := disableEcho(p.in)
+	if ok {
+		defer func() {
+			restore()
+			// the line break typed by the user was not echoed
+			_, _ = fmt.Fprintln(p.out)
+		}()
+	}
+	return p.readLine()
+}
+
+// readLine reads the next line. A last line without a line break is
+// returned as well; ErrNoInput is returned once the input is exhausted.
+func (p *Prompter) readLine() (string, error) {
+	line, err := p.reader.ReadString('\n')
+	if errors.Is(err, io.EOF) {
+		if line == "" {
+			return "", ErrNoInput
+		}
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
@@ -0,0 +1,28 @@
+package prompt
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/stdio"
+)
+
+func TestAsk_ReadsLines(t *testing.T) {
+	var out bytes.Buffer
+	p := New(stdio.Streams{In: strings.NewReader("one\r\n\nlast"), Out: &out})
+
+	for _, want := range []string{"one", "", "last"} {
+		got, err := p.Ask("? ")
+		if err != nil || got != want {
+			t.Fatalf("Ask = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := p.AskSecret("? "); !errors.Is(err, ErrNoInput) {
+		t.Errorf("expected ErrNoInput at the end of the input, got %v", err)
+	}
+	if out.String() != "? ? ? ? " {
+		t.Errorf("output = %q", out.String())
+	}
+}
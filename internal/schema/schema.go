@@ -0,0 +1,195 @@
+// Package schema derives configuration metadata from structs whose fields
+// carry `mapstructure` tags, so callers can address leaves by dotted key.
+//
+// Besides `mapstructure`, leaf fields may declare:
+//
+//	default:"123"   default value used for scaffolding, parsed with Coerce
+//	desc:"..."      human readable description of the field
+//	secret:"true"   marks a field holding credentials
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Field describes a leaf field of a configuration struct.
+type Field struct {
+	Key   string            // Key is the dotted mapstructure path (e.g. "common.var2")
+	Index []int             // Index is the field index path usable with reflect.Value.FieldByIndex
+	Type  reflect.Type      // Type is the Go type of the leaf field
+	Tag   reflect.StructTag // Tag is the raw struct tag of the leaf field
+}
+
+// Default returns the field's `default` tag converted to the field type,
+// or the zero value of the type when the tag is absent.
+func (f Field) Default() (interface{}, error) {
+	raw, ok := f.Tag.Lookup("default")
+	if !ok {
+		if f.Type.Kind() == reflect.Slice {
+			return reflect.MakeSlice(f.Type, 0, 0).Interface(), nil
+		}
+		return reflect.Zero(f.Type).Interface(), nil
+	}
+	return Coerce(f, raw)
+}
+
+// Description returns the field's `desc` tag.
+func (f Field) Description() string {
+	return f.Tag.Get("desc")
+}
+
+// Secret reports whether the field is marked with `secret:"true"`.
+func (f Field) Secret() bool {
+	secret, _ := strconv.ParseBool(f.Tag.Get("secret"))
+	return secret
+}
+
+// Fields returns every leaf field of v (a struct or pointer to struct) in
+// declaration order. Nested structs are flattened into dotted keys.
+func Fields(v interface{}) []Field {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+	return collect(t, "", nil)
+}
+
+// collect walks the struct type t recursively, prefixing keys with prefix.
+func collect(t reflect.Type, prefix string, index []int) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name := tagName(sf)
+		if name == "-" {
+			continue
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		idx := append(append([]int{}, index...), i)
+
+		if sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collect(sf.Type, key, idx)...)
+			continue
+		}
+		fields = append(fields, Field{Key: key, Index: idx, Type: sf.Type, Tag: sf.Tag})
+	}
+	return fields
+}
+
+// tagName returns the mapstructure name of sf, falling back to the
+// lower-cased Go field name like mapstructure does.
+func tagName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("mapstructure"), ",")
+	if name == "" {
+		return strings.ToLower(sf.Name)
+	}
+	return name
+}
+
+// Lookup returns the leaf field of v addressed by the dotted key.
+// Keys are matched case-insensitively, mirroring viper.
+func Lookup(v interface{}, key string) (Field, bool) {
+	key = strings.ToLower(key)
+	for _, f := range Fields(v) {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// Keys returns the dotted keys of every leaf field of v.
+func Keys(v interface{}) []string {
+	fields := Fields(v)
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.Key)
+	}
+	return keys
+}
+
+// Scaffold returns a nested map holding the default value of every leaf field
+// of v, keyed by mapstructure names. It fails if a `default` tag cannot be
+// converted to its field type.
+func Scaffold(v interface{}) (map[string]interface{}, error) {
+	data := map[string]interface{}{}
+	for _, f := range Fields(v) {
+		def, err := f.Default()
+		if err != nil {
+			return nil, fmt.Errorf("default tag: %w", err)
+		}
+		parts := strings.Split(f.Key, ".")
+		cur := data
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := cur[part].(map[string]interface{})
+			if !ok {
+				next = map[string]interface{}{}
+				cur[part] = next
+			}
+			cur = next
+		}
+		cur[parts[len(parts)-1]] = def
+	}
+	return data, nil
+}
+
+// Coerce converts raw into a value of the field's Go type.
+// Slices of strings are parsed as comma-separated lists.
+func Coerce(f Field, raw string) (interface{}, error) {
+	switch f.Type.Kind() {
+	case reflect.String:
+		return raw, nil
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, coerceError(f, raw)
+		}
+		return b, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, f.Type.Bits())
+		if err != nil {
+			return nil, coerceError(f, raw)
+		}
+		return reflect.ValueOf(n).Convert(f.Type).Interface(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 10, f.Type.Bits())
+		if err != nil {
+			return nil, coerceError(f, raw)
+		}
+		return reflect.ValueOf(n).Convert(f.Type).Interface(), nil
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(raw, f.Type.Bits())
+		if err != nil {
+			return nil, coerceError(f, raw)
+		}
+		return reflect.ValueOf(n).Convert(f.Type).Interface(), nil
+	case reflect.Slice:
+		if f.Type.Elem().Kind() == reflect.String {
+			if raw == "" {
+				return []string{}, nil
+			}
+			parts := strings.Split(raw, ",")
+			for i := range parts {
+				parts[i] = strings.TrimSpace(parts[i])
+			}
+			return parts, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported type %s for key %s", f.Type, f.Key)
+}
+
+// coerceError builds the error returned when raw cannot be parsed as f's type.
+func coerceError(f Field, raw string) error {
+	return fmt.Errorf("invalid value %q for %s: expected %s", raw, f.Key, f.Type.Kind())
+}
@@ -0,0 +1,135 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/schema"
+)
+
+type innerConfig struct {
+	Bar string `mapstructure:"bar"`
+}
+
+type testConfig struct {
+	Name    string      `mapstructure:"name"`
+	Count   int         `mapstructure:"count"`
+	Enabled bool        `mapstructure:"enabled"`
+	Ratio   float64     `mapstructure:"ratio"`
+	Tags    []string    `mapstructure:"tags"`
+	Inner   innerConfig `mapstructure:"inner"`
+	Skipped string      `mapstructure:"-"`
+	hidden  string
+}
+
+func TestKeys_FlattensNestedStructs(t *testing.T) {
+	got := schema.Keys(testConfig{})
+	want := []string{"name", "count", "enabled", "ratio", "tags", "inner.bar"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+
+	// pointers are dereferenced
+	if got := schema.Keys(&testConfig{}); !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys(&v) = %v, want %v", got, want)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	f, ok := schema.Lookup(testConfig{}, "Inner.Bar")
+	if !ok {
+		t.Fatal("expected inner.bar to be found")
+	}
+	if f.Key != "inner.bar" || f.Type.Kind() != reflect.String {
+		t.Errorf("unexpected field: %+v", f)
+	}
+
+	if _, ok := schema.Lookup(testConfig{}, "inner"); ok {
+		t.Error("expected non-leaf key to be rejected")
+	}
+	if _, ok := schema.Lookup(testConfig{}, "missing"); ok {
+		t.Error("expected missing key to be rejected")
+	}
+}
+
+func TestCoerce(t *testing.T) {
+	tests := []struct {
+		key  string
+		raw  string
+		want interface{}
+	}{
+		{"name", "abc", "abc"},
+		{"count", "42", 42},
+		{"enabled", "true", true},
+		{"ratio", "0.5", 0.5},
+		{"tags", "a, b", []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			f, _ := schema.Lookup(testConfig{}, tt.key)
+			got, err := schema.Coerce(f, tt.raw)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Coerce(%q) = %#v, want %#v", tt.raw, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoerce_InvalidValue(t *testing.T) {
+	f, _ := schema.Lookup(testConfig{}, "count")
+	if _, err := schema.Coerce(f, "abc"); err == nil {
+		t.Error("expected error for non-numeric int value")
+	}
+
+	f, _ = schema.Lookup(testConfig{}, "enabled")
+	if _, err := schema.Coerce(f, "maybe"); err == nil {
+		t.Error("expected error for invalid bool value")
+	}
+}
+
+type taggedConfig struct {
+	Token string      `mapstructure:"token" desc:"API token" secret:"true"`
+	Port  int         `mapstructure:"port" default:"8080"`
+	Tags  []string    `mapstructure:"tags"`
+	Inner innerConfig `mapstructure:"inner"`
+}
+
+func TestFieldMetadata(t *testing.T) {
+	token, _ := schema.Lookup(taggedConfig{}, "token")
+	if !token.Secret() || token.Description() != "API token" {
+		t.Errorf("unexpected token metadata: secret=%v desc=%q", token.Secret(), token.Description())
+	}
+	port, _ := schema.Lookup(taggedConfig{}, "port")
+	if port.Secret() {
+		t.Error("port should not be secret")
+	}
+}
+
+func TestScaffold_UsesDefaultTags(t *testing.T) {
+	got, err := schema.Scaffold(taggedConfig{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := map[string]interface{}{
+		"token": "",
+		"port":  8080,
+		"tags":  []string{},
+		"inner": map[string]interface{}{"bar": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Scaffold() = %#v, want %#v", got, want)
+	}
+}
+
+func TestScaffold_InvalidDefault(t *testing.T) {
+	type badConfig struct {
+		Port int `mapstructure:"port" default:"eighty"`
+	}
+	if _, err := schema.Scaffold(badConfig{}); err == nil {
+		t.Error("expected error for default tag not matching field type")
+	}
+}
@@ -0,0 +1,69 @@
+package schema
+
+import "strings"
+
+// Suggest returns the candidate closest to key by edit distance, or "" if
+// none is close enough to be a plausible typo. Comparison is case-insensitive.
+func Suggest(key string, candidates []string) string {
+	key = strings.ToLower(key)
+	best := ""
+	bestDist := -1
+	for _, c := range candidates {
+		d := levenshtein(key, strings.ToLower(c))
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	if best == "" || bestDist > maxTypoDistance(key) {
+		return ""
+	}
+	return best
+}
+
+// Sections returns the dotted keys of every nested struct of v, e.g. "hoge"
+// and "hoge.foo", so that typos in section names can be suggested too.
+func Sections(v interface{}) []string {
+	seen := map[string]bool{}
+	var sections []string
+	for _, f := range Fields(v) {
+		parts := strings.Split(f.Key, ".")
+		for i := 1; i < len(parts); i++ {
+			s := strings.Join(parts[:i], ".")
+			if !seen[s] {
+				seen[s] = true
+				sections = append(sections, s)
+			}
+		}
+	}
+	return sections
+}
+
+// maxTypoDistance is the largest edit distance still treated as a typo of key.
+func maxTypoDistance(key string) int {
+	if d := len(key) / 3; d > 2 {
+		return d
+	}
+	return 2
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,42 @@
+package schema_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/schema"
+)
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"common", "common.var1", "hoge.foo.bar"}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"comon", "common"},
+		{"common.var", "common.var1"},
+		{"Hoge.Fo.Bar", "hoge.foo.bar"},
+		{"completely-different", ""},
+	}
+	for _, tt := range tests {
+		if got := schema.Suggest(tt.key, candidates); got != tt.want {
+			t.Errorf("Suggest(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSections(t *testing.T) {
+	type deep struct {
+		Inner struct {
+			Leaf struct {
+				Value string `mapstructure:"value"`
+			} `mapstructure:"leaf"`
+		} `mapstructure:"inner"`
+		Top string `mapstructure:"top"`
+	}
+	got := schema.Sections(deep{})
+	want := []string{"inner", "inner.leaf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sections() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+package schema
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Violation describes a field value that breaks one of its validation rules.
+type Violation struct {
+	Key     string // Key is the dotted key of the offending field
+	Rule    string // Rule is the name of the broken rule (e.g. "min")
+	Message string // Message is a human readable description of the problem
+}
+
+// rule is a single parsed entry of a `validate` tag.
+type rule struct {
+	name string
+	arg  string
+}
+
+// Validate checks every leaf field of v against the rules declared in its
+// `validate` tag and returns the violations in field order. Supported rules:
+//
+//	required      the value must not be the zero value
+//	min=N, max=N  numeric bounds, or length bounds for strings and slices
+//	enum=a|b|c    the value must be one of the listed values
+//	url           the value must be an absolute URL with scheme and host
+//	regex=EXPR    the value must match EXPR; it must be the last rule
+//
+// Rules other than required are skipped for empty strings and slices.
+// Messages leave out the value of fields marked as secret.
+// An error is returned if a tag cannot be parsed.
+func Validate(v interface{}) ([]Violation, error) {
+	return ValidateRedacted(v, nil)
+}
+
+// ValidateRedacted is like Validate, but also leaves the value out of the
+// messages of the keys for which redact returns true, such as keys whose
+// value was resolved from a secret reference. redact may be nil.
+func ValidateRedacted(v interface{}, redact func(key string) bool) ([]Violation, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	var violations []Violation
+	for _, f := range Fields(v) {
+		rules, err := parseRules(f.Tag.Get("validate"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.Key, err)
+		}
+		value := rv.FieldByIndex(f.Index)
+		show := !f.Secret() && (redact == nil || !redact(f.Key))
+		for _, r := range rules {
+			msg, err := check(r, value, show)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", f.Key, err)
+			}
+			if msg != "" {
+				violations = append(violations, Violation{Key: f.Key, Rule: r.name, Message: msg})
+			}
+		}
+	}
+	return violations, nil
+}
+
+// parseRules splits a `validate` tag into rules. Everything after "regex="
+// belongs to the expression, so it may contain commas.
+func parseRules(tag string) ([]rule, error) {
+	var rules []rule
+	for tag != "" {
+		var item string
+		if strings.HasPrefix(tag, "regex=") {
+			item, tag = tag, ""
+		} else {
+			item, tag, _ = strings.Cut(tag, ",")
+		}
+		name, arg, _ := strings.Cut(strings.TrimSpace(item), "=")
+		switch name {
+		case "required", "url":
+		case "min", "max":
+			if _, err := strconv.ParseFloat(arg, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s rule argument %q", name, arg)
+			}
+		case "enum":
+			if arg == "" {
+				return nil, fmt.Errorf("enum rule requires values")
+			}
+		case "regex":
+			if _, err := regexp.Compile(arg); err != nil {
+				return nil, fmt.Errorf("invalid regex rule: %w", err)
+			}
+		default:
+			return nil, fmt.Errorf("unknown validation rule %q", name)
+		}
+		rules = append(rules, rule{name: name, arg: arg})
+	}
+	return rules, nil
+}
+
+// check applies r to value and returns a violation message, or "" if the
+// value satisfies the rule. The message quotes the value only if show is set.
+func check(r rule, value reflect.Value, show bool) (string, error) {
+	if r.name == "required" {
+		if value.IsZero() || (value.Kind() == reflect.Slice && value.Len() == 0) {
+			return "is required", nil
+		}
+		return "", nil
+	}
+	if value.IsZero() && (value.Kind() == reflect.String || value.Kind() == reflect.Slice) {
+		return "", nil
+	}
+
+	s := fmt.Sprint(value.Interface())
+	got := ""
+	if show {
+		got = fmt.Sprintf(" (got %q)", s)
+	}
+	switch r.name {
+	case "min", "max":
+		limit, _ := strconv.ParseFloat(r.arg, 64)
+		n, unit, ok := magnitude(value)
+		if !ok {
+			return "", fmt.Errorf("%s rule not supported for %s", r.name, value.Type())
+		}
+		if r.name == "min" && n < limit {
+			return fmt.Sprintf("must be at least %s%s", r.arg, unit), nil
+		}
+		if r.name == "max" && n > limit {
+			return fmt.Sprintf("must be at most %s%s", r.arg, unit), nil
+		}
+	case "enum":
+		allowed := strings.Split(r.arg, "|")
+		for _, a := range allowed {
+			if s == a {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of %s%s", strings.Join(allowed, ", "), got), nil
+	case "url":
+		u, err := url.Parse(s)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Sprintf("must be an absolute URL%s", got), nil
+		}
+	case "regex":
+		if !regexp.MustCompile(r.arg).MatchString(s) {
+			return fmt.Sprintf("must match %s%s", r.arg, got), nil
+		}
+	}
+	return "", nil
+}
+
+// magnitude returns the number compared by min/max rules: the value itself
+// for numbers, or the length (with a unit suffix) for strings and slices.
+func magnitude(value reflect.Value) (float64, string, bool) {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), "", true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "", true
+	case reflect.String:
+		return float64(len([]rune(value.String()))), " characters", true
+	case reflect.Slice:
+		return float64(value.Len()), " items", true
+	}
+	return 0, "", false
+}
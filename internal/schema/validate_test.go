@@ -0,0 +1,101 @@
+package schema_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/schema"
+)
+
+type validatedConfig struct {
+	Name    string   `mapstructure:"name" validate:"required,min=3,max=5"`
+	Port    int      `mapstructure:"port" validate:"min=1,max=65535"`
+	Mode    string   `mapstructure:"mode" validate:"enum=dev|prod"`
+	Server  string   `mapstructure:"server" validate:"url"`
+	Code    string   `mapstructure:"code" validate:"regex=^[a-z]{2,3}$"`
+	Targets []string `mapstructure:"targets" validate:"required"`
+}
+
+func TestValidate_ReportsEveryViolation(t *testing.T) {
+	cfg := validatedConfig{
+		Name:   "ab",
+		Port:   0,
+		Mode:   "test",
+		Server: "localhost",
+		Code:   "ABC",
+	}
+
+	got, err := schema.Validate(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var rules []string
+	for _, v := range got {
+		rules = append(rules, v.Key+":"+v.Rule)
+	}
+	want := []string{"name:min", "port:min", "mode:enum", "server:url", "code:regex", "targets:required"}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("violations = %v, want %v", rules, want)
+	}
+}
+
+func TestValidate_ValidConfig(t *testing.T) {
+	cfg := &validatedConfig{
+		Name:    "abcd",
+		Port:    8080,
+		Mode:    "prod",
+		Server:  "https://example.com",
+		Code:    "ab",
+		Targets: []string{"x"},
+	}
+	got, err := schema.Validate(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("expected no violations, got %v", got)
+	}
+}
+
+func TestValidate_SkipsEmptyOptionalStrings(t *testing.T) {
+	type optional struct {
+		Mode string `mapstructure:"mode" validate:"enum=dev|prod"`
+	}
+	got, err := schema.Validate(optional{})
+	if err != nil || len(got) != 0 {
+		t.Errorf("expected empty optional value to pass, got %v, %v", got, err)
+	}
+}
+
+func TestValidate_InvalidTag(t *testing.T) {
+	type bad struct {
+		Name string `mapstructure:"name" validate:"unique"`
+	}
+	if _, err := schema.Validate(bad{}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+}
+
+func TestValidate_LeavesOutSensitiveValues(t *testing.T) {
+	type sensitive struct {
+		Token string `mapstructure:"token" secret:"true" validate:"regex=^tk_"`
+		Mode  string `mapstructure:"mode" validate:"enum=dev|prod"`
+		Host  string `mapstructure:"host" validate:"url"`
+	}
+	cfg := sensitive{Token: "s3cr3t", Mode: "resolved", Host: "plain"}
+
+	got, err := schema.ValidateRedacted(cfg, func(key string) bool { return key == "mode" })
+	if err != nil || len(got) != 3 {
+		t.Fatalf("unexpected violations: %v, %v", got, err)
+	}
+	for _, v := range got[:2] {
+		if strings.Contains(v.Message, "s3cr3t") || strings.Contains(v.Message, "resolved") {
+			t.Errorf("expected the value of %s to be left out: %s", v.Key, v.Message)
+		}
+	}
+	if got[2].Message != `must be an absolute URL (got "plain")` {
+		t.Errorf("unexpected message for a plain value: %s", got[2].Message)
+	}
+}
@@ -0,0 +1,162 @@
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rising3/go-cli/internal/proc"
+)
+
+// Prefixes of secret references, which name where a value is read from
+// instead of holding it.
+const (
+	EnvPrefix  = "env:"  // env:NAME reads the environment variable NAME
+	FilePrefix = "file:" // file:PATH reads the file PATH; a leading ~ is the home directory
+	ExecPrefix = "exec:" // exec:COMMAND runs COMMAND and reads its standard output
+)
+
+// ExecTimeout bounds how long an exec: reference may run.
+var ExecTimeout = 10 * time.Second
+
+// Resolver resolves secret references.
+type Resolver struct {
+	// ExecCommand builds the process of exec: references; proc.ExecCommand
+	// when nil.
+	ExecCommand func(name string, arg ...string) *exec.Cmd
+}
+
+// IsReference reports whether v is a secret reference.
+func IsReference(v string) bool {
+	return strings.HasPrefix(v, EnvPrefix) || strings.HasPrefix(v, FilePrefix) || strings.HasPrefix(v, ExecPrefix)
+}
+
+// Resolve resolves ref with the zero Resolver.
+func Resolve(ref string) (string, error) {
+	return Resolver{}.Resolve(ref)
+}
+
+// Resolve returns the value the reference ref points to, without trailing
+// newlines. Values that are not references are returned unchanged. Errors
+// name the reference but never contain the resolved value.
+func (r Resolver) Resolve(ref string) (string, error) {
+	var value string
+	var err error
+	switch {
+	case strings.HasPrefix(ref, EnvPrefix):
+		value, err = resolveEnv(strings.TrimPrefix(ref, EnvPrefix))
+	case strings.HasPrefix(ref, FilePrefix):
+		value, err = resolveFile(strings.TrimPrefix(ref, FilePrefix))
+	case strings.HasPrefix(ref, ExecPrefix):
+		value, err = r.resolveExec(strings.TrimPrefix(ref, ExecPrefix))
+	default:
+		return ref, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	return strings.TrimRight(value, "\r\n"), nil
+}
+
+func resolveEnv(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("missing variable name")
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("environment variable %s is not set", name)
+	}
+	return value, nil
+}
+
+func resolveFile(path string) (string, error) {
+	if path == "" {
+		return "", errors.New("missing file path")
+	}
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (r Resolver) resolveExec(command string) (string, error) {
+	args, err := splitArgs(command)
+	if err != nil {
+		return "", err
+	}
+	if len(args) == 0 {
+		return "", errors.New("missing command")
+	}
+	execCommand := r.ExecCommand
+	if execCommand == nil {
+		execCommand = proc.ExecCommand
+	}
+	out, err := proc.Output(execCommand(args[0], args[1:]...), ExecTimeout)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+// splitArgs splits a command line into arguments separated by whitespace.
+// Single quotes keep their content literally; double quotes and backslashes
+// escape whitespace and quotes.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape in command")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
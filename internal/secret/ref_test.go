@@ -0,0 +1,69 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("TEST_SECRET", "from-env")
+	if err := os.WriteFile(filepath.Join(home, "id"), []byte("from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"plain":                             "plain",
+		"env:TEST_SECRET":                   "from-env",
+		"file:~/id":                         "from-file",
+		"file:" + filepath.Join(home, "id"): "from-file",
+		`exec:printf '%s\n' "a b"`:          "a b",
+	}
+	for ref, want := range tests {
+		got, err := Resolve(ref)
+		if err != nil || got != want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", ref, got, err, want)
+		}
+	}
+}
+
+func TestResolve_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	oldTimeout := ExecTimeout
+	ExecTimeout = 50 * time.Millisecond
+	t.Cleanup(func() { ExecTimeout = oldTimeout })
+
+	for _, ref := range []string{
+		"env:MYCLI_TEST_UNSET",
+		"env:",
+		"file:~/missing",
+		"exec:",
+		"exec:sh -c 'echo leaked; exit 1'",
+		"exec:sleep 5",
+		`exec:echo "unterminated`,
+	} {
+		_, err := Resolve(ref)
+		if err == nil {
+			t.Errorf("Resolve(%q): expected error", ref)
+			continue
+		}
+		if !strings.Contains(err.Error(), ref) {
+			t.Errorf("error should name the reference %q: %v", ref, err)
+		}
+		if strings.Contains(err.Error(), "leaked\n") {
+			t.Errorf("error must not contain the command output: %v", err)
+		}
+	}
+}
+
+func TestSplitArgs(t *testing.T) {
+	got, err := splitArgs(`pass show  "team/a b" 'x\y' c\ d`)
+	want := []string{"pass", "show", "team/a b", `x\y`, "c d"}
+	if err != nil || strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("splitArgs = %q, %v; want %q", got, err, want)
+	}
+}
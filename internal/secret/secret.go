@@ -0,0 +1,140 @@
+// Package secret encrypts configuration values at rest with AES-256-GCM and
+// a key derived from a passphrase with PBKDF2-SHA256.
+//
+// An encrypted value is stored as the envelope
+//
+//	enc:v1:<iterations>:<base64 salt>:<base64 nonce and ciphertext>
+//
+// Every envelope has its own random salt, so equal values encrypt differently.
+//
+// Alternatively a value can be a reference (env:, file: or exec:) that keeps
+// the secret out of the config file entirely; see Resolve.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Prefix marks an encrypted value.
+const Prefix = "enc:"
+
+// version is the envelope format written by Encrypt.
+const version = "v1"
+
+const (
+	saltSize = 16
+	keySize  = 32
+)
+
+// Iterations is the PBKDF2 iteration count used by Encrypt. It is recorded
+// in every envelope, so changing it does not affect existing values.
+var Iterations = 600000
+
+// MaxIterations bounds the iteration count Decrypt accepts from an envelope,
+// so that a tampered config file cannot stall every command in key
+// derivation.
+var MaxIterations = 10 * Iterations
+
+// ErrNoPassphrase is returned when a passphrase is required but empty.
+var ErrNoPassphrase = errors.New("passphrase is empty")
+
+// ErrDecrypt is returned when an envelope cannot be opened with the passphrase.
+var ErrDecrypt = errors.New("wrong passphrase or corrupted value")
+
+// IsEncrypted reports whether v is an encrypted envelope.
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, Prefix)
+}
+
+// Encrypt seals plaintext with a key derived from passphrase and returns the
+// envelope.
+func Encrypt(plaintext, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", ErrNoPassphrase
+	}
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(passphrase, salt, Iterations)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return strings.Join([]string{
+		strings.TrimSuffix(Prefix, ":"),
+		version,
+		strconv.Itoa(Iterations),
+		base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(sealed),
+	}, ":"), nil
+}
+
+// Decrypt opens an envelope produced by Encrypt.
+func Decrypt(envelope, passphrase string) (string, error) {
+	if passphrase == "" {
+		return "", ErrNoPassphrase
+	}
+	parts := strings.Split(strings.TrimPrefix(envelope, Prefix), ":")
+	if !IsEncrypted(envelope) || len(parts) != 4 {
+		return "", errors.New("malformed encrypted value")
+	}
+	if parts[0] != version {
+		return "", fmt.Errorf("unsupported encrypted value version: %s", parts[0])
+	}
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return "", errors.New("malformed encrypted value: invalid iteration count")
+	}
+	if iterations > MaxIterations {
+		return "", fmt.Errorf("malformed encrypted value: iteration count %d exceeds %d", iterations, MaxIterations)
+	}
+	salt, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", fmt.Errorf("malformed encrypted value: %w", err)
+	}
+
+	aead, err := newAEAD(passphrase, salt, iterations)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return "", ErrDecrypt
+	}
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", ErrDecrypt
+	}
+	return string(plaintext), nil
+}
+
+// newAEAD derives the key for passphrase and salt and returns its AES-GCM cipher.
+func newAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
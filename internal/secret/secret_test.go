@@ -0,0 +1,60 @@
+package secret_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/rising3/go-cli/internal/secret"
+)
+
+func init() {
+	// Keep key derivation fast; envelopes record the count they used.
+	secret.Iterations = 1000
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	env, err := secret.Encrypt("s3cr3t", "pass")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if !secret.IsEncrypted(env) || strings.Contains(env, "s3cr3t") {
+		t.Fatalf("unexpected envelope: %s", env)
+	}
+	if !strings.HasPrefix(env, "enc:v1:1000:") {
+		t.Errorf("envelope should record version and iterations: %s", env)
+	}
+
+	other, _ := secret.Encrypt("s3cr3t", "pass")
+	if other == env {
+		t.Error("expected a fresh salt and nonce per envelope")
+	}
+
+	got, err := secret.Decrypt(env, "pass")
+	if err != nil || got != "s3cr3t" {
+		t.Errorf("Decrypt = %q, %v", got, err)
+	}
+}
+
+func TestDecrypt_Errors(t *testing.T) {
+	env, _ := secret.Encrypt("x", "pass")
+
+	if _, err := secret.Decrypt(env, "wrong"); !errors.Is(err, secret.ErrDecrypt) {
+		t.Errorf("wrong passphrase: got %v", err)
+	}
+	if _, err := secret.Decrypt(env, ""); !errors.Is(err, secret.ErrNoPassphrase) {
+		t.Errorf("empty passphrase: got %v", err)
+	}
+	tampered := env[:len(env)-4] + "AAA="
+	if _, err := secret.Decrypt(tampered, "pass"); err == nil {
+		t.Error("expected error for tampered envelope")
+	}
+	for _, bad := range []string{"plain", "enc:v1:x", "enc:v9:1:AA==:AA==", "enc:v1:0:AA==:AA==", "enc:v1:1:!!:AA==", "enc:v1:99999999999:AA==:AA=="} {
+		if _, err := secret.Decrypt(bad, "pass"); err == nil {
+			t.Errorf("expected error for %q", bad)
+		}
+	}
+	if _, err := secret.Encrypt("x", ""); !errors.Is(err, secret.ErrNoPassphrase) {
+		t.Errorf("Encrypt with empty passphrase: got %v", err)
+	}
+}